@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	_ROOT      = "root"
+	_NAMESPACE = "namespace"
+	_DATABASE  = "database"
+	_SCOPE     = "scope"
+	_TOKEN     = "token"
+)
+
+// secure JSON data keys with this prefix are passed as scope signin parameters
+const scopeParameterPrefix = "scopeParameter."
+
+// returns the configured authentication method, by default the database user
+// signin is used, or the scope signin if a scope is configured
+func authMethod(config configuration) string {
+	if config.AuthMethod != "" {
+		return config.AuthMethod
+	}
+	if config.Scope != "" || config.Access != "" {
+		return _SCOPE
+	}
+	return _DATABASE
+}
+
+// returns the record access method, which replaces scopes in SurrealDB 2.x,
+// by default the access method is named like the configured scope
+// https://surrealdb.com/docs/surrealdb/surrealql/statements/define/access
+func accessMethod(config configuration) string {
+	if config.Version >= 2 && config.Access != "" {
+		return config.Access
+	}
+	return config.Scope
+}
+
+// https://docs.surrealdb.com/docs/integration/websocket/#signin
+// https://docs.surrealdb.com/docs/integration/websocket/#authenticate
+func authenticate(ctx context.Context, client *rpcClient, config configuration) error {
+	signinParameters := map[string]interface{}{
+		"user": config.Username,
+		"pass": config.Password,
+	}
+
+	method := authMethod(config)
+	switch method {
+	case _ROOT:
+	case _NAMESPACE:
+		signinParameters["NS"] = config.Namespace
+	case _DATABASE:
+		signinParameters["NS"] = config.Namespace
+		signinParameters["DB"] = config.Database
+	case _SCOPE:
+		if accessMethod(config) == "" {
+			return fmt.Errorf("authentication method '%s' requires a scope", method)
+		}
+		signinParameters["NS"] = config.Namespace
+		signinParameters["DB"] = config.Database
+		if config.Version >= 2 {
+			signinParameters["AC"] = accessMethod(config)
+		} else {
+			signinParameters["SC"] = config.Scope
+		}
+		for key, value := range config.ScopeParameters {
+			signinParameters[key] = value
+		}
+	case _TOKEN:
+		if config.Token == "" {
+			return fmt.Errorf("authentication method '%s' requires a token", method)
+		}
+	default:
+		return fmt.Errorf("unsupported authentication method '%s'", method)
+	}
+
+	if method == _TOKEN {
+		_, err := client.authenticate(ctx, config.Token)
+		if err != nil {
+			return fmt.Errorf("authenticate failed: %w", err)
+		}
+	} else {
+		_, err := client.signin(ctx, signinParameters)
+		if err != nil {
+			return fmt.Errorf("signin as %s user failed: %w", method, err)
+		}
+	}
+
+	// root and namespace users as well as tokens are not bound to a database
+	if method == _ROOT || method == _NAMESPACE || method == _TOKEN {
+		_, err := client.use(ctx, config.Namespace, config.Database)
+		if err != nil {
+			return fmt.Errorf("use namespace '%s' and database '%s' failed: %w", config.Namespace, config.Database, err)
+		}
+	}
+
+	return nil
+}
+
+// describes the configured authentication for the health check
+func describeAuth(config configuration) string {
+	description := describeAuthMethod(config)
+	if config.ForwardIdentity != "" {
+		description = fmt.Sprintf("%s, forwarding the Grafana user by %s", description, config.ForwardIdentity)
+	}
+	return description
+}
+
+func describeAuthMethod(config configuration) string {
+	method := authMethod(config)
+	switch method {
+	case _TOKEN:
+		return "token authentication"
+	case _SCOPE:
+		keys := make([]string, 0, len(config.ScopeParameters))
+		for key := range config.ScopeParameters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		kind := "scope"
+		if config.Version >= 2 {
+			kind = "access"
+		}
+		description := fmt.Sprintf("%s '%s' signin as '%s'", kind, accessMethod(config), config.Username)
+		if len(keys) > 0 {
+			description = fmt.Sprintf("%s with parameters %s", description, strings.Join(keys, ", "))
+		}
+		return description
+	default:
+		return fmt.Sprintf("%s user '%s'", method, config.Username)
+	}
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAuthenticate(t *testing.T) {
+	server := newFakeSurrealDB(t, 0, nil)
+	ctx := context.Background()
+
+	base := configuration{
+		Namespace: "main",
+		Database:  "metrics",
+		Username:  "grafana",
+		Password:  "secret",
+	}
+	use := rpcRequest{Method: "use", Params: []interface{}{"main", "metrics"}}
+
+	tests := []struct {
+		name     string
+		config   func(config configuration) configuration
+		expected []rpcRequest
+		invalid  bool
+	}{
+		{
+			"root 1.x",
+			func(config configuration) configuration { config.AuthMethod = _ROOT; return config },
+			[]rpcRequest{{Method: "signin", Params: []interface{}{map[string]interface{}{"user": "grafana", "pass": "secret"}}}, use},
+			false,
+		},
+		{
+			"namespace 2.x",
+			func(config configuration) configuration {
+				config.AuthMethod = _NAMESPACE
+				config.Version = 2
+				return config
+			},
+			[]rpcRequest{{Method: "signin", Params: []interface{}{map[string]interface{}{"user": "grafana", "pass": "secret", "NS": "main"}}}, use},
+			false,
+		},
+		{
+			"database by default",
+			func(config configuration) configuration { return config },
+			[]rpcRequest{{Method: "signin", Params: []interface{}{map[string]interface{}{"user": "grafana", "pass": "secret", "NS": "main", "DB": "metrics"}}}},
+			false,
+		},
+		{
+			"scope 1.x",
+			func(config configuration) configuration {
+				config.Scope = "viewer"
+				config.ScopeParameters = map[string]interface{}{"tenant": "acme"}
+				return config
+			},
+			[]rpcRequest{{Method: "signin", Params: []interface{}{map[string]interface{}{"user": "grafana", "pass": "secret", "NS": "main", "DB": "metrics", "SC": "viewer", "tenant": "acme"}}}},
+			false,
+		},
+		{
+			"scope 2.x named like the access method",
+			func(config configuration) configuration {
+				config.Scope = "viewer"
+				config.Version = 2
+				return config
+			},
+			[]rpcRequest{{Method: "signin", Params: []interface{}{map[string]interface{}{"user": "grafana", "pass": "secret", "NS": "main", "DB": "metrics", "AC": "viewer"}}}},
+			false,
+		},
+		{
+			"record access 2.x",
+			func(config configuration) configuration {
+				config.Scope = "viewer"
+				config.Access = "account"
+				config.Version = 2
+				return config
+			},
+			[]rpcRequest{{Method: "signin", Params: []interface{}{map[string]interface{}{"user": "grafana", "pass": "secret", "NS": "main", "DB": "metrics", "AC": "account"}}}},
+			false,
+		},
+		{
+			"token",
+			func(config configuration) configuration {
+				config.AuthMethod = _TOKEN
+				config.Token = "eyJhbGciOi"
+				config.Version = 2
+				return config
+			},
+			[]rpcRequest{{Method: "authenticate", Params: []interface{}{"eyJhbGciOi"}}, use},
+			false,
+		},
+		{
+			"scope without scope",
+			func(config configuration) configuration { config.AuthMethod = _SCOPE; return config },
+			[]rpcRequest{},
+			true,
+		},
+		{
+			"token without token",
+			func(config configuration) configuration { config.AuthMethod = _TOKEN; return config },
+			[]rpcRequest{},
+			true,
+		},
+		{
+			"unsupported method",
+			func(config configuration) configuration { config.AuthMethod = "oauth"; return config },
+			[]rpcRequest{},
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		server.recorded()
+
+		client, err := newRPCClient(ctx, server.location(), nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = authenticate(ctx, client, test.config(base))
+		client.close()
+
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected result '%v'", test.name, err)
+			continue
+		}
+
+		requests := server.recorded()
+		if reflect.DeepEqual(requests, test.expected) == false {
+			t.Errorf("%s: expected the requests '%v', got '%v'", test.name, test.expected, requests)
+		}
+	}
+}
+
+func TestDescribeAuth(t *testing.T) {
+	tests := []struct {
+		config   configuration
+		expected string
+	}{
+		{configuration{Username: "root", AuthMethod: _ROOT}, "root user 'root'"},
+		{configuration{Username: "grafana"}, "database user 'grafana'"},
+		{configuration{Username: "grafana", Scope: "viewer", ScopeParameters: map[string]interface{}{"tenant": "acme"}}, "scope 'viewer' signin as 'grafana' with parameters tenant"},
+		{configuration{Username: "grafana", Scope: "viewer", Access: "account", Version: 2}, "access 'account' signin as 'grafana'"},
+		{configuration{AuthMethod: _TOKEN, ForwardIdentity: _TOKEN}, "token authentication, forwarding the Grafana user by token"},
+	}
+
+	for _, test := range tests {
+		if description := describeAuth(test.config); description != test.expected {
+			t.Errorf("expected '%s', got '%s'", test.expected, description)
+		}
+	}
+}
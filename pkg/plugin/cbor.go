@@ -0,0 +1,327 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// SurrealDB 2.x custom CBOR tags
+// https://surrealdb.com/docs/surrealdb/integration/cbor
+const (
+	cborTagDatetime         = 0
+	cborTagNone             = 6
+	cborTagTable            = 7
+	cborTagRecordID         = 8
+	cborTagUUIDString       = 9
+	cborTagDecimal          = 10
+	cborTagDatetimeCompact  = 12
+	cborTagDurationString   = 13
+	cborTagDurationCompact  = 14
+	cborTagUUID             = 37
+	cborTagGeometryPoint    = 88
+	cborTagGeometryLine     = 89
+	cborTagGeometryPolygon  = 90
+	cborTagGeometryMultiPnt = 91
+	cborTagGeometryMultiLn  = 92
+	cborTagGeometryMultiPly = 93
+	cborTagGeometryColl     = 94
+)
+
+var cborDecMode = func() cbor.DecMode {
+	decMode, err := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}{}),
+		TimeTagToAny:   cbor.TimeTagToTime,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return decMode
+}()
+
+// decodes a CBOR message and maps the SurrealDB typed values to Go values,
+// which are handled by 'Datasource.frame()' as native field types
+func cborUnmarshal(message []byte, v interface{}) error {
+	var raw interface{}
+	err := cborDecMode.Unmarshal(message, &raw)
+	if err != nil {
+		return err
+	}
+
+	value, err := cborValue(raw)
+	if err != nil {
+		return err
+	}
+
+	switch target := v.(type) {
+	case *interface{}:
+		*target = value
+		return nil
+	case *rpcResponse:
+		// the result keeps the typed values, which a JSON roundtrip would lose
+		envelope, isEnvelope := value.(map[string]interface{})
+		if isEnvelope == false {
+			return fmt.Errorf("invalid response '%v'", value)
+		}
+		target.ID = envelope["id"]
+		target.Result = envelope["result"]
+		if envelope["error"] != nil {
+			target.Error = &rpcError{}
+			return remarshal(envelope["error"], target.Error)
+		}
+		return nil
+	default:
+		// reuse the JSON struct tags of the target, e.g. 'httpError'
+		return remarshal(value, v)
+	}
+}
+
+func cborValue(raw interface{}) (interface{}, error) {
+	switch value := raw.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, entry := range value {
+			converted, err := cborValue(entry)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, entry := range value {
+			converted, err := cborValue(entry)
+			if err != nil {
+				return nil, err
+			}
+			result[fmt.Sprintf("%v", key)] = converted
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for index, entry := range value {
+			converted, err := cborValue(entry)
+			if err != nil {
+				return nil, err
+			}
+			result[index] = converted
+		}
+		return result, nil
+	case uint64:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case big.Int:
+		// beyond 64 bits a float would lose the digits
+		return json.Number(value.String()), nil
+	case time.Time:
+		return value.UTC(), nil
+	case cbor.Tag:
+		return cborTagValue(value)
+	default:
+		return value, nil
+	}
+}
+
+func cborTagValue(tag cbor.Tag) (interface{}, error) {
+	content, err := cborValue(tag.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag.Number {
+	case cborTagNone:
+		return nil, nil
+	case cborTagTable, cborTagUUIDString:
+		return fmt.Sprintf("%v", content), nil
+	case cborTagDatetime:
+		return time.Parse(time.RFC3339Nano, fmt.Sprintf("%v", content))
+	case cborTagDatetimeCompact:
+		parts, isParts := content.([]interface{})
+		if isParts == false || len(parts) == 0 {
+			return nil, fmt.Errorf("invalid compact datetime '%v'", content)
+		}
+		seconds, _ := parts[0].(float64)
+		nanoseconds := float64(0)
+		if len(parts) > 1 {
+			nanoseconds, _ = parts[1].(float64)
+		}
+		return time.Unix(int64(seconds), int64(nanoseconds)).UTC(), nil
+	case cborTagDurationString:
+		return parseSurrealDuration(fmt.Sprintf("%v", content))
+	case cborTagDurationCompact:
+		parts, _ := content.([]interface{})
+		seconds := float64(0)
+		nanoseconds := float64(0)
+		if len(parts) > 0 {
+			seconds, _ = parts[0].(float64)
+		}
+		if len(parts) > 1 {
+			nanoseconds, _ = parts[1].(float64)
+		}
+		return time.Duration(seconds)*time.Second + time.Duration(nanoseconds), nil
+	case cborTagDecimal:
+		// the decimal keeps its precision, JSON marshals it as the exact number
+		decimal := fmt.Sprintf("%v", content)
+		if _, err := strconv.ParseFloat(decimal, 64); err != nil {
+			return decimal, nil
+		}
+		return json.Number(decimal), nil
+	case cborTagRecordID:
+		parts, isParts := content.([]interface{})
+		if isParts == false || len(parts) != 2 {
+			return fmt.Sprintf("%v", content), nil
+		}
+		return fmt.Sprintf("%s:%s", escapeIdent(fmt.Sprintf("%v", parts[0])), recordKey(parts[1])), nil
+	case cborTagUUID:
+		bytes, isBytes := content.([]byte)
+		if isBytes == false || len(bytes) != 16 {
+			return fmt.Sprintf("%v", content), nil
+		}
+		return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:16]), nil
+	case cborTagGeometryPoint:
+		return map[string]interface{}{"type": "Point", "coordinates": content}, nil
+	case cborTagGeometryLine:
+		return map[string]interface{}{"type": "LineString", "coordinates": geometryCoordinates(content)}, nil
+	case cborTagGeometryPolygon:
+		return map[string]interface{}{"type": "Polygon", "coordinates": geometryCoordinates(content)}, nil
+	case cborTagGeometryMultiPnt:
+		return map[string]interface{}{"type": "MultiPoint", "coordinates": geometryCoordinates(content)}, nil
+	case cborTagGeometryMultiLn:
+		return map[string]interface{}{"type": "MultiLineString", "coordinates": geometryCoordinates(content)}, nil
+	case cborTagGeometryMultiPly:
+		return map[string]interface{}{"type": "MultiPolygon", "coordinates": geometryCoordinates(content)}, nil
+	case cborTagGeometryColl:
+		return map[string]interface{}{"type": "GeometryCollection", "geometries": content}, nil
+	default:
+		return content, nil
+	}
+}
+
+// nested geometries are tagged as well, GeoJSON only keeps their coordinates
+func geometryCoordinates(content interface{}) interface{} {
+	entries, isEntries := content.([]interface{})
+	if isEntries == false {
+		return content
+	}
+
+	result := make([]interface{}, len(entries))
+	for index, entry := range entries {
+		if geometry, isGeometry := entry.(map[string]interface{}); isGeometry {
+			result[index] = geometry["coordinates"]
+		} else {
+			result[index] = entry
+		}
+	}
+	return result
+}
+
+// formats the record ID key like SurrealDB, e.g. 'metric:⟨a-1⟩' or 'metric:[1, 2]'
+func recordKey(key interface{}) string {
+	switch value := key.(type) {
+	case string:
+		return escapeIdent(value)
+	case json.Number:
+		return value.String()
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		bytes, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return string(bytes)
+	}
+}
+
+// escapes identifiers, which are not only letters, digits and underscores or
+// which are only digits, with angle brackets like SurrealDB
+func escapeIdent(value string) string {
+	plain := value != ""
+	digits := true
+	for _, char := range value {
+		isDigit := char >= '0' && char <= '9'
+		if isDigit == false && char != '_' && (char < 'a' || char > 'z') && (char < 'A' || char > 'Z') {
+			plain = false
+		}
+		if isDigit == false {
+			digits = false
+		}
+	}
+	if plain && digits == false {
+		return value
+	}
+	return "⟨" + strings.ReplaceAll(value, "⟩", "\\⟩") + "⟩"
+}
+
+// parses SurrealDB durations like '1w2d3h4m5s6ms7us8ns'
+func parseSurrealDuration(value string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"ns": time.Nanosecond,
+		"us": time.Microsecond,
+		"µs": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+		"y":  365 * 24 * time.Hour,
+	}
+
+	result := time.Duration(0)
+	rest := []rune(value)
+	for len(rest) > 0 {
+		index := 0
+		for index < len(rest) && rest[index] >= '0' && rest[index] <= '9' {
+			index++
+		}
+		if index == 0 {
+			return 0, fmt.Errorf("invalid duration '%s'", value)
+		}
+		number, err := strconv.ParseInt(string(rest[:index]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %w", value, err)
+		}
+		rest = rest[index:]
+
+		index = 0
+		for index < len(rest) && (rest[index] < '0' || rest[index] > '9') {
+			index++
+		}
+		unit, exists := units[string(rest[:index])]
+		if exists == false {
+			return 0, fmt.Errorf("invalid duration unit '%s' in '%s'", string(rest[:index]), value)
+		}
+		rest = rest[index:]
+
+		result = result + time.Duration(number)*unit
+	}
+
+	return result, nil
+}
+
+func cborMarshal(v interface{}) ([]byte, error) {
+	// the JSON struct tags of the RPC messages define the field names
+	var value interface{}
+	err := remarshal(v, &value)
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(value)
+}
+
+func remarshal(from interface{}, to interface{}) error {
+	bytes, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, to)
+}
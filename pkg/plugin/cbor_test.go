@@ -0,0 +1,179 @@
+package plugin
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestCBORUnmarshal(t *testing.T) {
+	message, err := cbor.Marshal(map[string]interface{}{
+		"id": "1",
+		"result": []interface{}{
+			map[string]interface{}{
+				"id":       cbor.Tag{Number: cborTagRecordID, Content: []interface{}{"metric", "a1"}},
+				"time":     cbor.Tag{Number: cborTagDatetimeCompact, Content: []interface{}{1700000000, 500}},
+				"created":  cbor.Tag{Number: cborTagDatetime, Content: "2023-11-14T22:13:20Z"},
+				"elapsed":  cbor.Tag{Number: cborTagDurationCompact, Content: []interface{}{90, 1}},
+				"timeout":  cbor.Tag{Number: cborTagDurationString, Content: "1m30s"},
+				"price":    cbor.Tag{Number: cborTagDecimal, Content: "1.25"},
+				"total":    cbor.Tag{Number: cborTagDecimal, Content: "12345678901234567890.123456789"},
+				"value":    42,
+				"missing":  cbor.Tag{Number: cborTagNone, Content: nil},
+				"location": cbor.Tag{Number: cborTagGeometryPoint, Content: []interface{}{1.5, 2.5}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response rpcResponse
+	err = cborUnmarshal(message, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Error != nil {
+		t.Fatalf("unexpected error '%v'", response.Error)
+	}
+
+	record := response.Result.([]interface{})[0].(map[string]interface{})
+
+	expected := map[string]interface{}{
+		"id":      "metric:a1",
+		"time":    time.Unix(1700000000, 500).UTC(),
+		"created": time.Unix(1700000000, 0).UTC(),
+		"elapsed": 90*time.Second + time.Nanosecond,
+		"timeout": 90 * time.Second,
+		"price":   json.Number("1.25"),
+		"value":   float64(42),
+		"total":   json.Number("12345678901234567890.123456789"),
+		"missing": nil,
+	}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("%s: expected '%v' (%T), got '%v' (%T)", key, value, value, record[key], record[key])
+		}
+	}
+
+	location := record["location"].(map[string]interface{})
+	if location["type"] != "Point" {
+		t.Errorf("location: unexpected geometry '%v'", location)
+	}
+}
+
+func TestCBORRecordID(t *testing.T) {
+	large, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+	tests := []struct {
+		table    interface{}
+		key      interface{}
+		expected string
+	}{
+		{"metric", "a1", "metric:a1"},
+		{"metric", 42, "metric:42"},
+		{"metric", "a-1", "metric:⟨a-1⟩"},
+		{"metric", "42", "metric:⟨42⟩"},
+		{"metric", "", "metric:⟨⟩"},
+		{"metric", "a⟩b", "metric:⟨a\\⟩b⟩"},
+		{"metric", large, "metric:123456789012345678901234567890"},
+		{"metric", []interface{}{"london", 1}, `metric:["london",1]`},
+		{"metric", map[string]interface{}{"city": "london"}, `metric:{"city":"london"}`},
+		{"cpu-usage", 1, "⟨cpu-usage⟩:1"},
+	}
+
+	for _, test := range tests {
+		message, err := cbor.Marshal(cbor.Tag{Number: cborTagRecordID, Content: []interface{}{test.table, test.key}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var value interface{}
+		err = cborUnmarshal(message, &value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != test.expected {
+			t.Errorf("%v: expected '%s', got '%v'", test.key, test.expected, value)
+		}
+	}
+}
+
+func TestCBORUnmarshalError(t *testing.T) {
+	message, err := cbor.Marshal(map[string]interface{}{
+		"id":    "1",
+		"error": map[string]interface{}{"code": -32000, "message": "There was a problem with the database"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var response rpcResponse
+	err = cborUnmarshal(message, &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Error == nil || response.Error.Code != -32000 {
+		t.Errorf("unexpected error '%v'", response.Error)
+	}
+}
+
+func TestParseSurrealDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		invalid  bool
+	}{
+		{"1h30m", 90 * time.Minute, false},
+		{"1w1d", 8 * 24 * time.Hour, false},
+		{"1s500ms", 1500 * time.Millisecond, false},
+		{"3µs2ns", 3002 * time.Nanosecond, false},
+		{"", 0, false},
+		{"5x", 0, true},
+		{"ms", 0, true},
+	}
+
+	for _, test := range tests {
+		result, err := parseSurrealDuration(test.value)
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected error '%v'", test.value, err)
+		}
+		if result != test.expected {
+			t.Errorf("%s: expected '%v', got '%v'", test.value, test.expected, result)
+		}
+	}
+}
+
+func TestNativeColumn(t *testing.T) {
+	timestamp := time.Unix(1700000000, 0).UTC()
+
+	values, unit, isNative := nativeColumn([]interface{}{timestamp, nil})
+	if isNative == false || unit != "" {
+		t.Fatalf("expected a time column, got '%v'", values)
+	}
+	if times := values.([]*time.Time); *times[0] != timestamp || times[1] != nil {
+		t.Errorf("unexpected times '%v'", times)
+	}
+
+	values, unit, isNative = nativeColumn([]interface{}{time.Minute, nil})
+	if isNative == false || unit != "s" {
+		t.Fatalf("expected a duration column, got '%v'", values)
+	}
+	if durations := values.([]*float64); *durations[0] != 60 || durations[1] != nil {
+		t.Errorf("unexpected durations '%v'", durations)
+	}
+
+	for _, cells := range [][]interface{}{
+		{timestamp, "text"},
+		{timestamp, time.Minute},
+		{nil, nil},
+		{float64(1)},
+	} {
+		if _, _, isNative := nativeColumn(cells); isNative {
+			t.Errorf("%v: unexpected native column", cells)
+		}
+	}
+}
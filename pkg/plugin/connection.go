@@ -0,0 +1,203 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	reconnectAttempts   = 5
+	reconnectBackoff    = 100 * time.Millisecond
+	reconnectBackoffMax = 5 * time.Second
+)
+
+// supervises the websocket of an authenticated RPC client, a broken socket is
+// replaced by a new connection, which repeats the signin and namespace use
+type connection struct {
+	location  string
+	tlsConfig *tls.Config
+	config    configuration
+
+	mutex  sync.Mutex
+	client *rpcClient
+	closed bool
+	// closed when the running reconnect is finished
+	reconnecting chan struct{}
+}
+
+func newConnection(ctx context.Context, location string, tlsConfig *tls.Config, config configuration) (*connection, error) {
+	c := &connection{
+		location:  location,
+		tlsConfig: tlsConfig,
+		config:    config,
+	}
+
+	client, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+
+	return c, nil
+}
+
+func (c *connection) connect(ctx context.Context) (*rpcClient, error) {
+	client, err := newRPCClient(ctx, c.location, c.tlsConfig, c.config.Version >= 2)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authenticate(ctx, client, c.config)
+	if err != nil {
+		client.close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// returns the current client, reconnecting with exponential backoff if the
+// websocket is broken, concurrent callers wait for the running reconnect
+func (c *connection) get(ctx context.Context) (*rpcClient, error) {
+	for {
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			return nil, errClosed
+		}
+		if c.client != nil && c.client.broken() == false {
+			client := c.client
+			c.mutex.Unlock()
+			return client, nil
+		}
+
+		reconnecting := c.reconnecting
+		if reconnecting == nil {
+			c.reconnecting = make(chan struct{})
+			c.mutex.Unlock()
+			return c.reconnect(ctx)
+		}
+		c.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-reconnecting:
+		}
+	}
+}
+
+// reconnects without holding the mutex, so that closing the connection is
+// not blocked by the backoff
+func (c *connection) reconnect(ctx context.Context) (*rpcClient, error) {
+	defer func() {
+		c.mutex.Lock()
+		close(c.reconnecting)
+		c.reconnecting = nil
+		c.mutex.Unlock()
+	}()
+
+	reconnectLog := logger(ctx)
+	reconnectLog.Warn("Connection lost, reconnecting", "location", c.location)
+
+	backoff := reconnectBackoff
+	for attempt := 1; ; attempt++ {
+		client, err := c.connect(ctx)
+		observeReconnect(c.config.UID, err)
+		if err == nil {
+			c.mutex.Lock()
+			if c.closed {
+				c.mutex.Unlock()
+				client.close()
+				return nil, errClosed
+			}
+			c.client = client
+			c.mutex.Unlock()
+
+			reconnectLog.Info("Connection reestablished", "location", c.location, "attempt", attempt)
+			return client, nil
+		}
+
+		reconnectLog.Warn("Reconnect failed", "location", c.location, "attempt", attempt, "error", err)
+
+		if attempt == reconnectAttempts {
+			return nil, fmt.Errorf("reconnect failed after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		c.mutex.Lock()
+		closed := c.closed
+		c.mutex.Unlock()
+		if closed {
+			return nil, errClosed
+		}
+
+		backoff = backoff * 2
+		if backoff > reconnectBackoffMax {
+			backoff = reconnectBackoffMax
+		}
+	}
+}
+
+// runs the query, a read query which failed because of a broken connection
+// is retried once on the reconnected websocket
+func (c *connection) query(ctx context.Context, query string) (interface{}, error) {
+	client, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.query(ctx, query, map[string]interface{}{})
+	if err == nil || errors.Is(err, errConnection) == false || readOnly(query) == false {
+		return result, err
+	}
+
+	logger(ctx).Info("Retrying read query after connection failure", "location", c.location)
+
+	client, err = c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.query(ctx, query, map[string]interface{}{})
+}
+
+func (c *connection) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	if c.client != nil {
+		c.client.close()
+	}
+}
+
+// statements which only read
+var readOnlyKeywords = map[string]bool{
+	"SELECT": true,
+	"RETURN": true,
+	"INFO":   true,
+	"SHOW":   true,
+	"USE":    true,
+}
+
+// reports if all statements of the query only read, a statement which writes
+// in a subquery like 'RETURN (CREATE ...)' makes the query not read only
+func readOnly(query string) bool {
+	statements := splitStatements(query)
+	for _, entry := range statements {
+		if readOnlyKeywords[entry.keyword()] == false || entry.writes() {
+			return false
+		}
+	}
+	return len(statements) > 0
+}
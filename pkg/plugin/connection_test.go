@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectionReconnect(t *testing.T) {
+	// signin and one query per websocket
+	server := newFakeSurrealDB(t, 2, nil)
+	ctx := context.Background()
+
+	db, err := newConnection(ctx, server.location(), nil, configuration{Username: "root", Password: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	for index := 0; index < 3; index++ {
+		_, err = db.query(ctx, "select * from metric")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if server.signins.Load() < 3 {
+		t.Errorf("expected a signin for every reconnect, got %d", server.signins.Load())
+	}
+}
+
+func TestConnectionClosedDuringReconnect(t *testing.T) {
+	server := newFakeSurrealDB(t, 2, nil)
+	ctx := context.Background()
+
+	db, err := newConnection(ctx, server.location(), nil, configuration{Username: "root", Password: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the server is gone, so the reconnect backs off between the attempts
+	db.client.close()
+	server.Close()
+
+	failed := make(chan error)
+	go func() {
+		_, err := db.get(ctx)
+		failed <- err
+	}()
+
+	// closing does not wait for the backoff of the reconnect
+	time.Sleep(50 * time.Millisecond)
+	closed := make(chan struct{})
+	go func() {
+		db.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("close blocked by the reconnect")
+	}
+
+	if err := <-failed; err == nil {
+		t.Errorf("expected the reconnect to fail")
+	}
+}
+
+func TestRPCInvalidResponse(t *testing.T) {
+	server := newFakeSurrealDB(t, 0, func(request rpcRequest) []byte {
+		// the ID is readable, the error is not a valid error object
+		if request.Method == "invalid" {
+			return []byte(`{"id":"` + request.ID + `","error":"boom"}`)
+		}
+		return []byte(`not json`)
+	})
+	ctx := context.Background()
+
+	client, err := newRPCClient(ctx, server.location(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.close()
+	client.timeout = time.Second
+
+	_, err = client.send(ctx, "invalid")
+	if err == nil || strings.Contains(err.Error(), "invalid response") == false || client.broken() {
+		t.Errorf("expected an invalid response for the request, got '%v'", err)
+	}
+
+	_, err = client.send(ctx, "unreadable")
+	if errors.Is(err, errConnection) == false || client.broken() == false {
+		t.Errorf("expected the client to fail, got '%v'", err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	tests := map[string]bool{
+		"select * from metric":                    true,
+		"SELECT * FROM a; return 1;":              true,
+		"info for db":                             true,
+		"select * from a; delete a":               false,
+		"create metric set value = 1":             false,
+		"let $a = (create metric); return $a":     false,
+		"return (create metric)":                  false,
+		"select * from (delete metric)":           false,
+		"select * from a where b = 'x; delete a'": true,
+		"select * from a -- ; delete a":           true,
+		"":                                        false,
+	}
+
+	for query, expected := range tests {
+		if readOnly(query) != expected {
+			t.Errorf("'%s': expected read only %v", query, expected)
+		}
+	}
+}
@@ -15,7 +15,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
-	"github.com/surrealdb/surrealdb.go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/backend
@@ -35,20 +35,88 @@ type datasourceOptions struct {
 	Database  string `json:"database"`
 	Scope     string `json:"scope"`
 	Username  string `json:"username"`
+
+	AuthMethod      string                 `json:"authMethod"`
+	ScopeParameters map[string]interface{} `json:"scopeParameters"`
+	ForwardIdentity string                 `json:"forwardIdentity"`
+
+	Transport string `json:"transport"`
+	Debug     bool   `json:"debug"`
+	MaxRows   int    `json:"maxRows"`
+	Version   string `json:"surrealVersion"`
+	Access    string `json:"access"`
+
+	AllowedNamespaces []string `json:"allowedNamespaces"`
+	AllowedDatabases  []string `json:"allowedDatabases"`
+
+	Endpoints        []string `json:"endpoints"`
+	EndpointStrategy string   `json:"endpointStrategy"`
+	EndpointCooldown string   `json:"endpointCooldown"`
+
+	PoolMinSize             *int   `json:"poolMinSize"`
+	PoolMaxSize             *int   `json:"poolMaxSize"`
+	PoolIdleTimeout         string `json:"poolIdleTimeout"`
+	PoolHealthCheckInterval string `json:"poolHealthCheckInterval"`
+
+	// https://grafana.com/docs/grafana/latest/administration/provisioning/#json-data
+	Scheme            string `json:"scheme"`
+	TLSAuth           bool   `json:"tlsAuth"`
+	TLSAuthWithCACert bool   `json:"tlsAuthWithCACert"`
+	TLSSkipVerify     bool   `json:"tlsSkipVerify"`
+	TLSServerName     string `json:"serverName"`
 }
 
 type Datasource struct {
-	db     *surrealdb.DB
-	config configuration
+	db       transport
+	config   configuration
+	sessions sessions
 }
 
 type configuration struct {
+	UID string
+
 	Location  string
 	Namespace string
 	Database  string
 	Scope     string
 	Username  string
 	Password  string
+
+	AuthMethod      string
+	ScopeParameters map[string]interface{}
+	Token           string
+	ForwardIdentity string
+
+	// names of the scope parameters from the secure JSON data
+	SecretScopeParameters []string
+
+	Transport string
+	Pool      poolOptions
+	Debug     bool
+
+	// hard row limit of the queries, zero is unlimited
+	MaxRows int
+
+	// SurrealDB major version and the detected release, e.g. '2.0.4'
+	Version int
+	Release string
+	Access  string
+
+	AllowedNamespaces []string
+	AllowedDatabases  []string
+
+	Endpoints        []string
+	EndpointStrategy string
+	EndpointCooldown time.Duration
+
+	Scheme            string
+	TLSAuth           bool
+	TLSAuthWithCACert bool
+	TLSSkipVerify     bool
+	TLSServerName     string
+	TLSCACert         string
+	TLSClientCert     string
+	TLSClientKey      string
 }
 
 // https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/backend#DataSourceInstanceSettings
@@ -63,12 +131,14 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 	}
 
 	config := configuration{
+		UID:       settings.UID,
 		Location:  "localhost:8000",
 		Namespace: "default",
 		Database:  "default",
 		Scope:     "",
 		Username:  "root",
 		Password:  "root",
+		Scheme:    _WS,
 	}
 
 	if jsonData.Location != "" {
@@ -86,47 +156,116 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 	if jsonData.Username != "" {
 		config.Username = jsonData.Username
 	}
+	if jsonData.Scheme != "" {
+		config.Scheme = jsonData.Scheme
+	}
+	if scheme, location, found := strings.Cut(config.Location, "://"); found {
+		config.Scheme = scheme
+		config.Location = location
+	}
+
+	config.Endpoints, err = parseEndpoints(config.Scheme, jsonData.Endpoints)
+	if err != nil {
+		log.DefaultLogger.Error("Endpoints", "Error", err)
+		return undefined, err
+	}
+	if len(config.Endpoints) > 0 {
+		config.Location = config.Endpoints[0]
+	}
+
+	config.EndpointStrategy = jsonData.EndpointStrategy
+	err = checkEndpointStrategy(config.EndpointStrategy)
+	if err != nil {
+		return undefined, err
+	}
+	if jsonData.EndpointCooldown != "" {
+		config.EndpointCooldown, err = time.ParseDuration(jsonData.EndpointCooldown)
+		if err != nil || config.EndpointCooldown <= 0 {
+			return undefined, fmt.Errorf("invalid endpoint cooldown '%s'", jsonData.EndpointCooldown)
+		}
+	}
+
+	config.AuthMethod = jsonData.AuthMethod
+	config.ForwardIdentity = jsonData.ForwardIdentity
+	config.Transport = jsonData.Transport
+	config.Debug = jsonData.Debug
+	config.MaxRows = jsonData.MaxRows
+	if config.MaxRows < 0 {
+		return undefined, fmt.Errorf("invalid row limit '%d'", jsonData.MaxRows)
+	}
+	config.Access = jsonData.Access
+	config.AllowedNamespaces = jsonData.AllowedNamespaces
+	config.AllowedDatabases = jsonData.AllowedDatabases
+	config.ScopeParameters = map[string]interface{}{}
+	for key, value := range jsonData.ScopeParameters {
+		config.ScopeParameters[key] = value
+	}
+
+	config.TLSAuth = jsonData.TLSAuth
+	config.TLSAuthWithCACert = jsonData.TLSAuthWithCACert
+	config.TLSSkipVerify = jsonData.TLSSkipVerify
+	config.TLSServerName = jsonData.TLSServerName
 
 	var secureData = settings.DecryptedSecureJSONData
 	if secureData != nil {
 		if secureData["password"] != "" {
 			config.Password = secureData["password"]
 		}
+		config.Token = secureData["token"]
+		for key, value := range secureData {
+			// a secret reset in the config editor is kept as empty value
+			if name, found := strings.CutPrefix(key, scopeParameterPrefix); found && value != "" {
+				config.ScopeParameters[name] = value
+				config.SecretScopeParameters = append(config.SecretScopeParameters, name)
+			}
+		}
+		config.TLSCACert = secureData["tlsCACert"]
+		config.TLSClientCert = secureData["tlsClientCert"]
+		config.TLSClientKey = secureData["tlsClientKey"]
 	}
 
-	location := fmt.Sprintf("ws://%s/rpc", config.Location)
-	db, err := surrealdb.New(location)
+	_, err = newTLSConfig(config)
 	if err != nil {
+		log.DefaultLogger.Error("TLS", "Error", err)
 		return undefined, err
 	}
 
-	// https://docs.surrealdb.com/docs/integration/websocket/#signin
-	signinParameters := map[string]interface{}{
-		"NS":   config.Namespace,
-		"DB":   config.Database,
-		"user": config.Username,
-		"pass": config.Password,
+	config.Pool, err = newPoolOptions(jsonData)
+	if err != nil {
+		log.DefaultLogger.Error("Pool", "Error", err)
+		return undefined, err
 	}
-	if config.Scope != "" {
-		signinParameters["SC"] = config.Scope
+
+	switch config.ForwardIdentity {
+	case "", _SCOPE, _TOKEN:
+	default:
+		return undefined, fmt.Errorf("unsupported identity forwarding '%s'", config.ForwardIdentity)
 	}
 
-	_, err = db.Signin(signinParameters)
+	config.Version, config.Release, err = resolveVersion(ctx, jsonData, config)
 	if err != nil {
+		log.DefaultLogger.Error("Version", "Error", err)
 		return undefined, err
 	}
 
 	r := &Datasource{
-		db:     db,
 		config: config,
 	}
 
+	db, err := newTransport(ctx, config, config.Pool)
+	if err != nil {
+		return undefined, err
+	}
+
+	r.db = db
+
 	return r, nil
 }
 
 // https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt#InstanceDisposer
 func (r *Datasource) Dispose() {
-	r.db.Close()
+	r.closeSessions()
+	r.db.close()
 }
 
 // https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/backend#CheckHealthHandler
@@ -134,32 +273,82 @@ func (r *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRe
 	var status = backend.HealthStatusOk
 	var message = "Data source is working"
 
-	query := `
-return
-{ database : session::db()
-, namespace : session::ns()
-, origin : session::origin()
-}
-`
-
-	_, err := r.query(query)
+	details, err := r.diagnose(ctx, r.db)
 	if err != nil {
 		status = backend.HealthStatusError
 		message = "Data source unhealthy: " + err.Error()
+		details.Error = err.Error()
+	} else if len(details.Warnings) > 0 {
+		message = fmt.Sprintf("%s, but %s", message, strings.Join(details.Warnings, ", "))
+	} else {
+		message = fmt.Sprintf("%s, %d tables in %.1fms", message, len(details.Tables), details.LatencyMs)
+	}
+
+	message = fmt.Sprintf(
+		"%s (%s, %s://%s, %s/%s, %s, %s)",
+		message,
+		details.Version,
+		r.config.Scheme,
+		r.config.Location,
+		details.Namespace,
+		details.Database,
+		describeTLS(r.config),
+		describeAuth(r.config),
+	)
+
+	jsonDetails, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
 	}
 
 	return &backend.CheckHealthResult{
-		Status:  status,
-		Message: message,
+		Status:      status,
+		Message:     message,
+		JSONDetails: jsonDetails,
 	}, nil
 }
 
 // https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/backend#QueryDataHandler
 func (r *Datasource) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	ctx, span := startSpan(ctx, "QueryData", attribute.Int("queries", len(req.Queries)))
+	defer span.End()
+
+	ctx = withRequestLogContext(ctx, req.PluginContext)
+
 	response := backend.NewQueryDataResponse()
 
+	db, err := r.session(ctx, req.PluginContext, req)
+	if err != nil {
+		for _, q := range req.Queries {
+			response.Responses[q.RefID] = backend.ErrDataResponse(
+				backend.StatusUnauthorized,
+				fmt.Sprintf("Session failed: %v", err.Error()),
+			)
+		}
+		return response, nil
+	}
+
 	for _, q := range req.Queries {
-		res := r.queryData(ctx, req.PluginContext, q)
+		queryDB, err := r.target(ctx, req.PluginContext, req, db, q)
+		if err != nil {
+			response.Responses[q.RefID] = backend.ErrDataResponse(
+				backend.StatusForbidden,
+				fmt.Sprintf("Query target: %v", err.Error()),
+			)
+			continue
+		}
+
+		mode := queryModeLabel(q)
+		observe := r.observeQuery(mode)
+		queryCtx, querySpan := startSpan(ctx, "queryData",
+			attribute.String("refId", q.RefID),
+			attribute.String("mode", mode),
+		)
+
+		res := r.queryData(queryCtx, req.PluginContext, queryDB, q)
+
+		endQuerySpan(querySpan, res)
+		observe(res)
 		response.Responses[q.RefID] = res
 	}
 
@@ -170,22 +359,66 @@ type queryRequestData struct {
 	Hide          bool     `json:"hide"` // inherited
 	Mode          string   `json:"mode"`
 	SurQL         string   `json:"surql"`
+	Namespace     string   `json:"namespace"`
+	Database      string   `json:"database"`
 	Requery       bool     `json:"requery"`
 	Timestamp     string   `json:"timestamp"`
 	LogMessage    string   `json:"logMessage"`
 	MetricData    string   `json:"metricData"`
 	Group         bool     `json:"group"`
 	GroupBy       string   `json:"groupBy"`
+	GroupRankBy   string   `json:"groupRankBy"`
+	GroupOther    bool     `json:"groupOther"`
+	GroupOrder    string   `json:"groupOrder"`
+	TopK          int      `json:"topK"`
+	BottomK       int      `json:"bottomK"`
 	Rate          bool     `json:"rate"`
 	RateZero      bool     `json:"rateZero"`
 	RateInterval  string   `json:"rateInterval"`
 	RateFunctions []string `json:"rateFunctions"`
+
+	Transforms []queryTransformData `json:"transforms"`
+
+	HistogramBuckets string    `json:"histogramBuckets"`
+	HistogramStart   float64   `json:"histogramStart"`
+	HistogramWidth   float64   `json:"histogramWidth"`
+	HistogramFactor  float64   `json:"histogramFactor"`
+	HistogramCount   int       `json:"histogramCount"`
+	HistogramBounds  []float64 `json:"histogramBounds"`
+	HistogramFormat  string    `json:"histogramFormat"`
+
+	Downsample  string `json:"downsample"`
+	FrameFormat string `json:"frameFormat"`
+	Geo         bool   `json:"geo"`
+
+	Limit    int    `json:"limit"`
+	Paginate bool   `json:"paginate"`
+	Cursor   string `json:"cursor"`
+
+	ExplainFull bool `json:"explainFull"`
+
+	GraphTitle         string `json:"graphTitle"`
+	GraphMainStat      string `json:"graphMainStat"`
+	GraphSecondaryStat string `json:"graphSecondaryStat"`
+	GraphColor         string `json:"graphColor"`
+
+	TraceSearch        bool   `json:"traceSearch"`
+	TraceID            string `json:"traceId"`
+	TraceSpanID        string `json:"traceSpanId"`
+	TraceParentSpanID  string `json:"traceParentSpanId"`
+	TraceServiceName   string `json:"traceServiceName"`
+	TraceOperationName string `json:"traceOperationName"`
+	TraceStartTime     string `json:"traceStartTime"`
+	TraceDuration      string `json:"traceDuration"`
+	TraceTags          string `json:"traceTags"`
 }
 
 type queryResponseData struct {
 	status interface{}
 	time   interface{}
 	result interface{}
+	// bytes of the received response messages
+	size int
 }
 
 type queryData struct {
@@ -197,9 +430,12 @@ type queryData struct {
 	interval time.Duration
 	name     string
 	mode     QueryMode
+	logger   log.Logger
+
+	maxDataPoints int64
 }
 
-func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext, dataQuery backend.DataQuery) backend.DataResponse {
+func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext, db transport, dataQuery backend.DataQuery) backend.DataResponse {
 
 	queryTimeNow := time.Now().UTC()
 
@@ -215,10 +451,6 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 	queryName := dataQuery.RefID
 	queryInterval := dataQuery.Interval
 
-	// log.DefaultLogger.Info(
-	// 	fmt.Sprintf("query: %s", dataQuery.JSON),
-	// )
-
 	var queryRequest queryRequestData
 
 	err := json.Unmarshal(dataQuery.JSON, &queryRequest)
@@ -233,6 +465,9 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 		return backend.DataResponse{}
 	}
 
+	ctx = withQueryLogContext(ctx, dataQuery.RefID, queryRequest.SurQL)
+	queryLog := logger(ctx)
+
 	queryMode, err := NewQueryMode(queryRequest.Mode)
 	if err != nil {
 		return backend.ErrDataResponse(
@@ -255,13 +490,104 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 		queryRequest.GroupBy = "group"
 	}
 
+	if queryRequest.GraphTitle == "" {
+		queryRequest.GraphTitle = "name"
+	}
+
+	if queryRequest.GraphMainStat == "" {
+		queryRequest.GraphMainStat = "mainStat"
+	}
+
+	if queryRequest.GraphSecondaryStat == "" {
+		queryRequest.GraphSecondaryStat = "secondaryStat"
+	}
+
+	if queryRequest.GraphColor == "" {
+		queryRequest.GraphColor = "color"
+	}
+
+	if queryRequest.TraceID == "" {
+		queryRequest.TraceID = "traceID"
+	}
+
+	if queryRequest.TraceSpanID == "" {
+		queryRequest.TraceSpanID = "spanID"
+	}
+
+	if queryRequest.TraceParentSpanID == "" {
+		queryRequest.TraceParentSpanID = "parentSpanID"
+	}
+
+	if queryRequest.TraceServiceName == "" {
+		queryRequest.TraceServiceName = "serviceName"
+	}
+
+	if queryRequest.TraceOperationName == "" {
+		queryRequest.TraceOperationName = "operationName"
+	}
+
+	if queryRequest.TraceStartTime == "" {
+		queryRequest.TraceStartTime = "startTime"
+	}
+
+	if queryRequest.TraceDuration == "" {
+		queryRequest.TraceDuration = "duration"
+	}
+
+	if queryRequest.TraceTags == "" {
+		queryRequest.TraceTags = "tags"
+	}
+
+	_, macroSpan := startSpan(ctx, "macros")
 	surql = strings.Replace(surql, "$interval", queryInterval.String(), -1)
 	surql = strings.Replace(surql, "$now", "'"+queryTimeNow.Format(time.RFC3339Nano)+"'", -1)
 	surql = strings.Replace(surql, "$from", "'"+queryTimeFrom.Format(time.RFC3339Nano)+"'", -1)
 	surql = strings.Replace(surql, "$to", "'"+queryTimeTo.Format(time.RFC3339Nano)+"'", -1)
+	endSpan(macroSpan, nil)
 
-	queryResponse, err := r.query(surql)
+	if queryMode == ExplainQueryMode {
+		surql, err = explainQuery(surql, queryRequest.ExplainFull)
+		if err != nil {
+			return backend.ErrDataResponse(
+				backend.StatusBadRequest,
+				fmt.Sprintf("Explain failed: %v", err.Error()),
+			)
+		}
+	}
+
+	// the row limits apply to the result of every mode before it is
+	// processed, the pagination only to the modes returning the rows as they
+	// are, the explained statement can not be wrapped
+	if queryMode != RawQueryMode && queryMode != LogQueryMode {
+		queryRequest.Paginate = false
+	}
+	queryLimit := rowLimit(r.config.MaxRows, queryRequest)
+	queryOffset := 0
+	queryWrapped := false
+	if queryRequest.Paginate {
+		queryOffset, err = decodeCursor(queryRequest.Cursor)
+		if err != nil {
+			return backend.ErrDataResponse(
+				backend.StatusBadRequest,
+				fmt.Sprintf("Pagination failed: %v", err.Error()),
+			)
+		}
+	}
+	if queryLimit > 0 && queryMode != ExplainQueryMode {
+		surql, queryWrapped = limitQuery(surql, queryLimit, queryOffset)
+	}
+
+	if r.config.Debug {
+		queryLog.Info("Query expanded", "mode", queryMode, "surql", redact(r.config, surql))
+	}
+
+	queryStart := time.Now()
+	queryResponse, err := r.query(ctx, db, surql)
+	queryDuration := time.Since(queryStart)
 	if err != nil {
+		if r.config.Debug {
+			queryLog.Info("Query failed", "duration", queryDuration, "error", redact(r.config, err.Error()))
+		}
 		return backend.ErrDataResponse(
 			backend.StatusBadRequest,
 			fmt.Sprintf("Query failed: %v", err.Error()),
@@ -277,6 +603,9 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 		interval: queryInterval,
 		name:     queryName,
 		mode:     queryMode,
+		logger:   queryLog,
+
+		maxDataPoints: dataQuery.MaxDataPoints,
 	}
 
 	var preferredVisualization data.VisType
@@ -287,6 +616,19 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 		preferredVisualization = data.VisTypeLogs
 	case MetricQueryMode:
 		preferredVisualization = data.VisTypeGraph
+	case HistogramQueryMode:
+		// the SDK provides no heatmap visualization type, the frame type
+		// 'heatmap-rows' or 'heatmap-cells' is detected by the heatmap panel
+		preferredVisualization = data.VisTypeGraph
+	case ExplainQueryMode:
+		preferredVisualization = data.VisTypeTable
+	case GraphQueryMode:
+		preferredVisualization = data.VisTypeNodeGraph
+	case TraceQueryMode:
+		preferredVisualization = data.VisTypeTrace
+		if queryRequest.TraceSearch {
+			preferredVisualization = data.VisTypeTable
+		}
 	default:
 		// TODO: @ppaulweber: provide more modes in the future
 		// https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/data#pkg-constants
@@ -296,29 +638,51 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 		)
 	}
 
+	frameMetaCustom := newFrameMetaCustom(queryRequest.SurQL, surql, queryResponse)
+
+	var notices []data.Notice
+	if queryLimit > 0 {
+		var truncated bool
+		query.response.result, truncated = limitResult(query.response.result, queryLimit, queryOffset, queryWrapped)
+		if truncated {
+			notices = []data.Notice{limitNotice(queryRequest, queryLimit, queryOffset)}
+			if queryRequest.Paginate {
+				frameMetaCustom.Cursor = encodeCursor(queryOffset + queryLimit)
+			}
+		}
+		if queryRequest.Paginate && limitOrdered(surql) == false {
+			notices = append(notices, orderNotice())
+		}
+	}
+
 	// https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/data#FrameMeta
 	frameMeta := &data.FrameMeta{
 		PreferredVisualization: preferredVisualization,
-		Custom: struct {
-			QueryRaw string `json:"queryRaw"`
-			QueryRun string `json:"queryRun"`
-			Status   string `json:"status"`
-			Time     string `json:"time"`
-			// Result   string `json:"result"`
-			// Request  string `json:"request"`
-		}{
-			QueryRaw: fmt.Sprintf("%s", queryRequest.SurQL),
-			QueryRun: fmt.Sprintf("%s", surql),
-			Status:   fmt.Sprintf("%s", queryResponse.status),
-			Time:     fmt.Sprintf("%s", queryResponse.time),
-			// Result:   fmt.Sprintf("%s", queryResponse.result),
-			// Request:  fmt.Sprintf("%s", dataQuery.JSON),
-		},
+		ExecutedQueryString:    surql,
+		Custom:                 frameMetaCustom,
+		Notices:                notices,
 	}
 
 	var dataResponse backend.DataResponse
 
-	err = r.process(&query, query.name, query.response.result, frameMeta, &dataResponse)
+	switch queryMode {
+	case ExplainQueryMode:
+		err = traced(ctx, "explain", func() error {
+			return r.explain(&query, frameMeta, &dataResponse)
+		})
+	case GraphQueryMode:
+		err = traced(ctx, "graph", func() error {
+			return r.graph(&query, frameMeta, &dataResponse)
+		})
+	case TraceQueryMode:
+		err = traced(ctx, "trace", func() error {
+			return r.trace(&query, frameMeta, &dataResponse)
+		})
+	default:
+		err = traced(ctx, "process", func() error {
+			return r.process(&query, query.name, query.response.result, frameMeta, &dataResponse)
+		})
+	}
 	if err != nil {
 		return backend.ErrDataResponse(
 			backend.StatusBadRequest,
@@ -327,7 +691,9 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 	}
 
 	if queryMode == MetricQueryMode {
-		err = r.metric(&query, &dataResponse)
+		err = traced(ctx, "metric", func() error {
+			return r.metric(&query, &dataResponse)
+		})
 		if err != nil {
 			return backend.ErrDataResponse(
 				backend.StatusBadRequest,
@@ -336,7 +702,9 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 		}
 
 		if query.request.Group {
-			err = r.metricGroup(&query, &dataResponse)
+			err = traced(ctx, "metricGroup", func() error {
+				return r.metricGroup(&query, &dataResponse)
+			})
 			if err != nil {
 				return backend.ErrDataResponse(
 					backend.StatusBadRequest,
@@ -346,16 +714,103 @@ func (r *Datasource) queryData(ctx context.Context, pCtx backend.PluginContext,
 		}
 
 		if query.request.Rate {
-			for _, frame := range dataResponse.Frames {
-				err = r.metricRate(&query, frame)
-				if err != nil {
-					return backend.ErrDataResponse(
-						backend.StatusBadRequest,
-						fmt.Sprintf("Rate failed: %v", err.Error()),
-					)
+			err = traced(ctx, "metricRate", func() error {
+				for _, frame := range dataResponse.Frames {
+					err := r.metricRate(&query, frame)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return backend.ErrDataResponse(
+					backend.StatusBadRequest,
+					fmt.Sprintf("Rate failed: %v", err.Error()),
+				)
+			}
+		}
+
+		if query.request.Rate == false && query.request.Downsample != "" {
+			err = traced(ctx, "metricDownsample", func() error {
+				for _, frame := range dataResponse.Frames {
+					err := r.metricDownsample(&query, frameMeta, frame)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return backend.ErrDataResponse(
+					backend.StatusBadRequest,
+					fmt.Sprintf("Downsample failed: %v", err.Error()),
+				)
+			}
+		}
+
+		if len(query.request.Transforms) > 0 {
+			err = traced(ctx, "metricTransform", func() error {
+				for _, frame := range dataResponse.Frames {
+					err := r.metricTransform(&query, frame)
+					if err != nil {
+						return err
+					}
 				}
+				return nil
+			})
+			if err != nil {
+				return backend.ErrDataResponse(
+					backend.StatusBadRequest,
+					fmt.Sprintf("Transform failed: %v", err.Error()),
+				)
 			}
 		}
+
+		err = traced(ctx, "metricFrameFormat", func() error {
+			return r.metricFrameFormat(&query, frameMeta, &dataResponse)
+		})
+		if err != nil {
+			return backend.ErrDataResponse(
+				backend.StatusBadRequest,
+				fmt.Sprintf("Frame format failed: %v", err.Error()),
+			)
+		}
+	}
+
+	if queryMode == HistogramQueryMode {
+		err = traced(ctx, "metric", func() error {
+			return r.metric(&query, &dataResponse)
+		})
+		if err != nil {
+			return backend.ErrDataResponse(
+				backend.StatusBadRequest,
+				fmt.Sprintf("Metric failed: %v", err.Error()),
+			)
+		}
+
+		err = traced(ctx, "histogram", func() error {
+			return r.histogram(&query, &dataResponse)
+		})
+		if err != nil {
+			return backend.ErrDataResponse(
+				backend.StatusBadRequest,
+				fmt.Sprintf("Histogram failed: %v", err.Error()),
+			)
+		}
+	}
+
+	frameStats(dataResponse.Frames, frameMeta, queryResponse, time.Since(queryStart)-queryDuration)
+
+	if r.config.Debug {
+		queryLog.Info(
+			"Query processed",
+			"status", queryResponse.status,
+			"serverTime", queryResponse.time,
+			"queryDuration", queryDuration,
+			"processDuration", time.Since(queryStart)-queryDuration,
+			"frames", frameShapes(dataResponse.Frames),
+		)
 	}
 
 	return dataResponse
@@ -385,6 +840,17 @@ func (r *Datasource) process(query *queryData, name string, result interface{},
 		return nil
 	}
 
+	if value, isValue := result.(json.Number); isValue {
+		frame, err := r.result(query, name, value)
+		if err != nil {
+			return err
+		}
+
+		frame.Meta = frameMeta
+		dataResponse.Frames = append(dataResponse.Frames, frame)
+		return nil
+	}
+
 	if value, isValue := result.(float64); isValue {
 		frame, err := r.result(query, name, value)
 		if err != nil {
@@ -441,8 +907,6 @@ func (r *Datasource) table(query *queryData, name string, array []interface{}) (
 	table := make([]map[string]interface{}, 0)
 
 	for _, entry := range array {
-		// log.DefaultLogger.Info(fmt.Sprintf("entry: %s", entry))
-
 		if entryMap, isEntryMap := entry.(map[string]interface{}); isEntryMap {
 			row := make(map[string]interface{})
 			for key, value := range entryMap {
@@ -454,7 +918,6 @@ func (r *Datasource) table(query *queryData, name string, array []interface{}) (
 				}
 
 				row[key] = value
-				// log.DefaultLogger.Info(fmt.Sprintf("key->value: %s->%s", key, value))
 			}
 
 			table = append(table, row)
@@ -470,6 +933,7 @@ func (r *Datasource) frame(query *queryData, name string, head map[string]int, t
 	frame := data.NewFrame(name)
 
 	columns := make(map[string][]string)
+	cells := make(map[string][]interface{})
 	for _, row := range table {
 		for key, _ := range head {
 
@@ -481,7 +945,10 @@ func (r *Datasource) frame(query *queryData, name string, head map[string]int, t
 			value := ""
 
 			cell, cellExists := row[key]
-			if cellExists {
+			cells[key] = append(cells[key], cell)
+			if duration, isDuration := cell.(time.Duration); isDuration {
+				value = duration.String()
+			} else if cellExists {
 				cellBytes, err := json.Marshal(cell)
 				if err != nil {
 					value = fmt.Sprintf("< %s >", cell)
@@ -513,7 +980,7 @@ func (r *Datasource) frame(query *queryData, name string, head map[string]int, t
 
 			if err != nil {
 				timestamps[tsIndex] = nil
-				log.DefaultLogger.Error(err.Error())
+				queryLogger(query).Error("Invalid timestamp", "field", timestampKey, "error", err)
 			} else {
 				timestamps[tsIndex] = &timestamp
 			}
@@ -523,8 +990,24 @@ func (r *Datasource) frame(query *queryData, name string, head map[string]int, t
 		fields[timestampKey] = timestamps
 	}
 
+	if query.request.Geo {
+		geo, geoKeys := geoFields(columns, cells)
+		for _, key := range geoKeys {
+			delete(columns, key)
+		}
+		for key, field := range geo {
+			fields[key] = field
+			keys = append(keys, key)
+		}
+	}
+
+	units := make(map[string]string)
 	for key, column := range columns {
 		fields[key] = column
+		if values, unit, isNative := nativeColumn(cells[key]); isNative {
+			fields[key] = values
+			units[key] = unit
+		}
 		if key == "id" {
 			continue
 		}
@@ -546,6 +1029,9 @@ func (r *Datasource) frame(query *queryData, name string, head map[string]int, t
 		field = r.typeConversion(field)
 
 		dataField := data.NewField(key, nil, field)
+		if units[key] != "" {
+			dataField.Config = &data.FieldConfig{Unit: units[key]}
+		}
 
 		frame.Fields = append(
 			frame.Fields,
@@ -556,6 +1042,38 @@ func (r *Datasource) frame(query *queryData, name string, head map[string]int, t
 	return frame, nil
 }
 
+// typed values of the SurrealDB 2.x CBOR protocol are mapped to native field
+// types, datetimes to time fields and durations to seconds
+func nativeColumn(cells []interface{}) (interface{}, string, bool) {
+	times := make([]*time.Time, len(cells))
+	durations := make([]*float64, len(cells))
+	timeCount := 0
+	durationCount := 0
+
+	for index, cell := range cells {
+		switch value := cell.(type) {
+		case nil:
+		case time.Time:
+			times[index] = &value
+			timeCount++
+		case time.Duration:
+			seconds := value.Seconds()
+			durations[index] = &seconds
+			durationCount++
+		default:
+			return nil, "", false
+		}
+	}
+
+	if timeCount > 0 && durationCount == 0 {
+		return times, "", true
+	}
+	if durationCount > 0 && timeCount == 0 {
+		return durations, "s", true
+	}
+	return nil, "", false
+}
+
 func (r *Datasource) typeConversion(field interface{}) interface{} {
 	fieldArray, isFieldArray := field.([]string)
 	if isFieldArray == false || len(fieldArray) == 0 {
@@ -580,44 +1098,45 @@ func (r *Datasource) typeConversion(field interface{}) interface{} {
 	return result
 }
 
-func (r *Datasource) query(query string) (queryResponseData, error) {
+func (r *Datasource) query(ctx context.Context, db transport, query string) (response queryResponseData, err error) {
 	var undefined queryResponseData
 
-	queryResponse, err := r.db.Query(query, map[string]interface{}{})
+	ctx, span := startSpan(ctx, "query", attribute.String("db.system", "surrealdb"))
+	defer func() {
+		span.SetAttributes(
+			attribute.String("surrealdb.status", fmt.Sprintf("%v", response.status)),
+			attribute.String("surrealdb.time", fmt.Sprintf("%v", response.time)),
+		)
+		endSpan(span, err)
+	}()
+
+	ctx, size := withResponseSize(ctx)
+	queryResponse, err := db.query(ctx, query)
 	if err != nil {
+		observeRPCError(r.config.UID, err)
 		return undefined, err
 	}
 
-	// log.DefaultLogger.Info(fmt.Sprintf("queryResponse: %s", queryResponse))
-
 	array, isArray := queryResponse.([]interface{})
 	if len(array) == 0 {
 		return undefined, fmt.Errorf("invalid queryResponse length")
 	}
 
-	// log.DefaultLogger.Info(fmt.Sprintf("arrResp: %s", arrResp))
-
 	dataMap, ok := array[len(array)-1].(map[string]interface{})
 	if ok == false || isArray == false {
 		return undefined, fmt.Errorf("invalid queryResponse array type")
 	}
 
-	// log.DefaultLogger.Info(fmt.Sprintf("dataMap: %s", dataMap))
-
 	status, ok := dataMap["status"]
 	if ok == false {
 		return undefined, fmt.Errorf("invalid queryResponse status")
 	}
 
-	// log.DefaultLogger.Info(fmt.Sprintf("status: %s", status))
-
 	responseTime, ok := dataMap["time"]
 	if ok == false {
 		return undefined, fmt.Errorf("invalid queryResponse time")
 	}
 
-	// log.DefaultLogger.Info(fmt.Sprintf("time: %s", responseTime))
-
 	result, ok := dataMap["result"]
 	if ok == false {
 		return undefined, fmt.Errorf("invalid queryResponse data")
@@ -627,6 +1146,7 @@ func (r *Datasource) query(query string) (queryResponseData, error) {
 		status: status,
 		time:   responseTime,
 		result: result,
+		size:   int(size.Load()),
 	}, nil
 }
 
@@ -639,6 +1159,7 @@ func (r *Datasource) metric(query *queryData, dataResponse *backend.DataResponse
 	var timeField *data.Field
 	var dataField *data.Field
 	var groupByField *data.Field
+	otherFields := []*data.Field{}
 
 	timeFieldName := query.request.Timestamp
 	dataFieldName := query.request.MetricData
@@ -666,6 +1187,7 @@ func (r *Datasource) metric(query *queryData, dataResponse *backend.DataResponse
 			groupByField = field
 			continue
 		}
+		otherFields = append(otherFields, field)
 	}
 
 	if len(frame.Fields) == 0 {
@@ -699,6 +1221,14 @@ func (r *Datasource) metric(query *queryData, dataResponse *backend.DataResponse
 
 	if query.request.Group {
 		frame.Fields = append(frame.Fields, groupByField)
+		return nil
+	}
+
+	// without group the long and wide formats keep the other fields as labels
+	// and values of the series, see 'metricSeries()'
+	format := query.request.FrameFormat
+	if query.mode == MetricQueryMode && (format == _LONG || format == _WIDE) {
+		dataResponse.Frames = metricSeries(frame, otherFields)
 	}
 
 	return nil
@@ -717,12 +1247,16 @@ func (r *Datasource) metricGroup(query *queryData, dataResponse *backend.DataRes
 	groupByField := frame.Fields[2] // see 'metric()'
 
 	groups := map[string]struct{}{}
+	groupKeys := []string{}
 	groupTimeMap := map[string][]*time.Time{}
 	groupDataMap := map[string][]*float64{}
 
 	index := 0
 	for index < groupByField.Len() {
 		key := fmt.Sprintf("%s", groupByField.At(index))
+		if _, exists := groups[key]; exists == false {
+			groupKeys = append(groupKeys, key)
+		}
 		groups[key] = struct{}{}
 
 		groupTime := timeField.At(index).(*time.Time)
@@ -743,9 +1277,70 @@ func (r *Datasource) metricGroup(query *queryData, dataResponse *backend.DataRes
 		index++
 	}
 
+	selected, err := groupLimit(query, groupDataMap)
+	if err != nil {
+		return err
+	}
+
+	if selected != nil {
+		for key, _ := range groups {
+			if selected[key] == false {
+				delete(groups, key)
+			}
+		}
+	}
+
+	folded := false
+	if selected != nil && query.request.GroupOther {
+		// keep the record order of the remaining groups, see 'metricRate()'
+		otherTime := []*time.Time{}
+		otherData := []*float64{}
+
+		index = 0
+		for index < groupByField.Len() {
+			key := fmt.Sprintf("%s", groupByField.At(index))
+			if selected[key] == false {
+				otherTime = append(otherTime, timeField.At(index).(*time.Time))
+				otherData = append(otherData, dataField.At(index).(*float64))
+			}
+			index++
+		}
+
+		// a group with the same name conflicts, even if it is folded itself
+		if contains(groupKeys, groupOtherName) && len(otherTime) > 0 {
+			return fmt.Errorf("group '%s' conflicts with the folded remaining groups", groupOtherName)
+		}
+
+		// without rate the folded values are summed per timestamp, the rate
+		// functions need the records of the remaining groups
+		if query.request.Rate == false {
+			otherTime, otherData = groupFold(otherTime, otherData)
+		}
+
+		if len(otherTime) > 0 {
+			groups[groupOtherName] = struct{}{}
+			groupTimeMap[groupOtherName] = otherTime
+			groupDataMap[groupOtherName] = otherData
+			folded = true
+		}
+	}
+
+	groupKeys, err = groupOrder(query, groupKeys, groupDataMap)
+	if err != nil {
+		return err
+	}
+
+	if folded {
+		groupKeys = append(groupKeys, groupOtherName)
+	}
+
 	dataResponse.Frames = []*data.Frame{}
 
-	for key, _ := range groups {
+	for _, key := range groupKeys {
+		if _, exists := groups[key]; exists == false {
+			continue
+		}
+
 		groupFrame := data.NewFrame(key)
 
 		groupTimeField := data.NewField(timeField.Name, nil, groupTimeMap[key])
@@ -764,23 +1359,8 @@ func (r *Datasource) metricRate(query *queryData, frame *data.Frame) error {
 	timeField := frame.Fields[0] // see 'metric()'
 	dataField := frame.Fields[1] // see 'metric()'
 
-	from_ns := query.timeFrom.UnixNano()
-	to_ns := query.timeTo.UnixNano()
-	interval_ns := query.interval.Nanoseconds()
-
 	zeroVector := query.request.RateZero
 
-	queryRateInterval := query.request.RateInterval
-	if queryRateInterval != "" {
-		queryRateInterval = strings.Replace(queryRateInterval, "$interval", query.interval.String(), -1)
-
-		rateInterval, err := time.ParseDuration(queryRateInterval)
-		if err != nil {
-			return fmt.Errorf("invalid interval '%s': %w", queryRateInterval, err)
-		}
-		interval_ns = rateInterval.Nanoseconds()
-	}
-
 	rateFunctions := map[string]bool{
 		"count":      false,
 		"absence":    false,
@@ -802,9 +1382,11 @@ func (r *Datasource) metricRate(query *queryData, frame *data.Frame) error {
 		rateFunctions[rateFunction] = true
 	}
 
-	index := 0
+	timeData, bucketData, err := r.metricBuckets(query, timeField, dataField)
+	if err != nil {
+		return err
+	}
 
-	timeData := []time.Time{}
 	rateData := []*int64{}
 
 	absenceData := []*float64{}
@@ -817,33 +1399,8 @@ func (r *Datasource) metricRate(query *queryData, frame *data.Frame) error {
 	quantile95Data := []*float64{}
 	quantile99Data := []*float64{}
 
-	for current_ns := from_ns; current_ns <= to_ns; current_ns += interval_ns {
-		count := int64(0)
-		values := []*float64{}
-
-		for index < timeField.Len() {
-			record_time := timeField.At(index).(*time.Time)
-			record_time_ns := record_time.UnixNano()
-			record_value := dataField.At(index).(*float64)
-			index++
-
-			if record_time_ns < current_ns {
-				continue
-			}
-
-			if record_time_ns > (current_ns + interval_ns) {
-				index--
-				break
-			}
-
-			count++
-
-			values = append(values, record_value)
-		}
-
-		current := time.Unix(0, int64(current_ns))
-
-		timeData = append(timeData, current)
+	for _, values := range bucketData {
+		count := int64(len(values))
 
 		if (count == 0) && (zeroVector == false) {
 			rateData = append(rateData, nil)
@@ -862,63 +1419,125 @@ func (r *Datasource) metricRate(query *queryData, frame *data.Frame) error {
 		quantile99Data = append(quantile99Data, quantile(0.99, values, zeroVector))
 	}
 
+	// the labels of the series, see 'metricSeries()'
+	labels := dataField.Labels
+
 	timeField = data.NewField(timeField.Name, nil, timeData)
 
 	frame.Fields = []*data.Field{timeField}
 
 	if enabled, exists := rateFunctions["count"]; exists && enabled {
-		countField := data.NewField("count", nil, rateData)
+		countField := data.NewField("count", labels, rateData)
 		frame.Fields = append(frame.Fields, countField)
 	}
 
 	if enabled, exists := rateFunctions["sum"]; exists && enabled {
-		sumField := data.NewField("sum", nil, sumData)
+		sumField := data.NewField("sum", labels, sumData)
 		frame.Fields = append(frame.Fields, sumField)
 	}
 
 	if enabled, exists := rateFunctions["absence"]; exists && enabled {
-		absenceField := data.NewField("absence", nil, absenceData)
+		absenceField := data.NewField("absence", labels, absenceData)
 		frame.Fields = append(frame.Fields, absenceField)
 	}
 
 	if enabled, exists := rateFunctions["average"]; exists && enabled {
-		averageField := data.NewField("average", nil, averageData)
+		averageField := data.NewField("average", labels, averageData)
 		frame.Fields = append(frame.Fields, averageField)
 	}
 
 	if enabled, exists := rateFunctions["median"]; exists && enabled {
-		quantile50Field := data.NewField("median", nil, quantile50Data)
+		quantile50Field := data.NewField("median", labels, quantile50Data)
 		frame.Fields = append(frame.Fields, quantile50Field)
 	}
 
 	if enabled, exists := rateFunctions["quantile25"]; exists && enabled {
-		quantile25Field := data.NewField("quantile25", nil, quantile25Data)
+		quantile25Field := data.NewField("quantile25", labels, quantile25Data)
 		frame.Fields = append(frame.Fields, quantile25Field)
 	}
 
 	if enabled, exists := rateFunctions["quantile75"]; exists && enabled {
-		quantile75Field := data.NewField("quantile75", nil, quantile75Data)
+		quantile75Field := data.NewField("quantile75", labels, quantile75Data)
 		frame.Fields = append(frame.Fields, quantile75Field)
 	}
 
 	if enabled, exists := rateFunctions["quantile95"]; exists && enabled {
-		quantile95Field := data.NewField("quantile95", nil, quantile95Data)
+		quantile95Field := data.NewField("quantile95", labels, quantile95Data)
 		frame.Fields = append(frame.Fields, quantile95Field)
 	}
 
 	if enabled, exists := rateFunctions["quantile99"]; exists && enabled {
-		quantile99Field := data.NewField("quantile99", nil, quantile99Data)
+		quantile99Field := data.NewField("quantile99", labels, quantile99Data)
 		frame.Fields = append(frame.Fields, quantile99Field)
 	}
 
 	if enabled, exists := rateFunctions["stddev"]; exists && enabled {
-		stdDevField := data.NewField("stddev", nil, stdDevData)
+		stdDevField := data.NewField("stddev", labels, stdDevData)
 		frame.Fields = append(frame.Fields, stdDevField)
 	}
 
 	return nil
 }
 
+// splits the time ordered records into buckets of the rate interval, which
+// cover the query time range, and returns the bucket start times and values
+func (r *Datasource) metricBuckets(query *queryData, timeField *data.Field, dataField *data.Field) ([]time.Time, [][]*float64, error) {
+	from_ns := query.timeFrom.UnixNano()
+	to_ns := query.timeTo.UnixNano()
+	interval_ns := query.interval.Nanoseconds()
+
+	queryRateInterval := query.request.RateInterval
+	if queryRateInterval != "" {
+		queryRateInterval = strings.Replace(queryRateInterval, "$interval", query.interval.String(), -1)
+
+		rateInterval, err := time.ParseDuration(queryRateInterval)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid interval '%s': %w", queryRateInterval, err)
+		}
+		interval_ns = rateInterval.Nanoseconds()
+	}
+
+	if interval_ns <= 0 {
+		return nil, nil, fmt.Errorf("invalid interval '%s'", time.Duration(interval_ns))
+	}
+
+	index := 0
+
+	timeData := []time.Time{}
+	bucketData := [][]*float64{}
+
+	for current_ns := from_ns; current_ns <= to_ns; current_ns += interval_ns {
+		values := []*float64{}
+
+		for index < timeField.Len() {
+			record_time := timeField.At(index).(*time.Time)
+			record_time_ns := record_time.UnixNano()
+			record_value := dataField.At(index).(*float64)
+			index++
+
+			if record_time_ns < current_ns {
+				continue
+			}
+
+			if record_time_ns > (current_ns + interval_ns) {
+				index--
+				break
+			}
+
+			values = append(values, record_value)
+		}
+
+		current := time.Unix(0, int64(current_ns))
+
+		timeData = append(timeData, current)
+		bucketData = append(bucketData, values)
+	}
+
+	metricRateBuckets.WithLabelValues(r.config.UID).Observe(float64(len(timeData)))
+
+	return timeData, bucketData, nil
+}
+
 var zero = float64(0)
 var one = float64(1)
 
@@ -0,0 +1,201 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// LTTB keeps the first and the last record and at least one in between
+const minimumDownsampleThreshold = 3
+
+// reduces the records of the frame to the 'MaxDataPoints' of the panel,
+// the time and data field are expected at index 0 and 1 (see 'metric()')
+func (r *Datasource) metricDownsample(query *queryData, frameMeta *data.FrameMeta, frame *data.Frame) error {
+	switch query.request.Downsample {
+	case "lttb", "minmax":
+	default:
+		return fmt.Errorf("unsupported downsample method '%s'", query.request.Downsample)
+	}
+
+	threshold := int(query.maxDataPoints)
+	if threshold <= 0 || len(frame.Fields) < 2 {
+		return nil
+	}
+
+	timeField := frame.Fields[0]
+	dataField := frame.Fields[1]
+
+	length := timeField.Len()
+	if length <= threshold {
+		return nil
+	}
+
+	indices := []int{}
+	times := []float64{}
+	values := []float64{}
+
+	for index := 0; index < length; index++ {
+		timestamp := fieldTime(timeField, index)
+		if timestamp == nil {
+			continue
+		}
+
+		value, err := dataField.NullableFloatAt(index)
+		if err != nil {
+			return fmt.Errorf("field '%s' is not numeric: %w", dataField.Name, err)
+		}
+		if value == nil {
+			continue
+		}
+
+		indices = append(indices, index)
+		times = append(times, float64(timestamp.UnixNano()))
+		values = append(values, *value)
+	}
+
+	if threshold < minimumDownsampleThreshold {
+		threshold = minimumDownsampleThreshold
+	}
+	if len(indices) <= threshold {
+		return nil
+	}
+
+	var selected []int
+	switch query.request.Downsample {
+	case "lttb":
+		selected = downsampleLTTB(times, values, threshold)
+	case "minmax":
+		selected = downsampleMinMax(times, values, threshold)
+	}
+
+	fields := make([]*data.Field, len(frame.Fields))
+	for fieldIndex, field := range frame.Fields {
+		fields[fieldIndex] = data.NewFieldFromFieldType(field.Type(), len(selected))
+		fields[fieldIndex].Name = field.Name
+		fields[fieldIndex].Labels = field.Labels
+		fields[fieldIndex].Config = field.Config
+
+		for position, index := range selected {
+			fields[fieldIndex].Set(position, field.At(indices[index]))
+		}
+	}
+	frame.Fields = fields
+
+	// the meta data is shared between the frames of a response, the series of
+	// a group have none and start from the meta data of the query
+	meta := *frameMeta
+	if frame.Meta != nil {
+		meta = *frame.Meta
+	}
+	meta.Notices = append([]data.Notice{}, meta.Notices...)
+	frame.Meta = &meta
+
+	frame.AppendNotices(data.Notice{
+		Severity: data.NoticeSeverityInfo,
+		Text: fmt.Sprintf(
+			"Data downsampled with '%s' from %d to %d points",
+			query.request.Downsample,
+			length,
+			len(selected),
+		),
+	})
+
+	return nil
+}
+
+// expects more records than the threshold, which is at least three
+// https://skemman.is/bitstream/1946/15343/3/SS_MSthesis.pdf
+func downsampleLTTB(times []float64, values []float64, threshold int) []int {
+	length := len(times)
+
+	selected := make([]int, 0, threshold)
+	selected = append(selected, 0)
+
+	bucketSize := float64(length-2) / float64(threshold-2)
+
+	previous := 0
+	for bucket := 0; bucket < threshold-2; bucket++ {
+		nextStart := int(math.Floor(float64(bucket+1)*bucketSize)) + 1
+		nextEnd := int(math.Floor(float64(bucket+2)*bucketSize)) + 1
+		if nextEnd > length {
+			nextEnd = length
+		}
+
+		averageTime := float64(0)
+		averageValue := float64(0)
+		for index := nextStart; index < nextEnd; index++ {
+			averageTime = averageTime + times[index]
+			averageValue = averageValue + values[index]
+		}
+		if nextEnd > nextStart {
+			averageTime = averageTime / float64(nextEnd-nextStart)
+			averageValue = averageValue / float64(nextEnd-nextStart)
+		}
+
+		start := int(math.Floor(float64(bucket)*bucketSize)) + 1
+		end := nextStart
+
+		maximumArea := float64(-1)
+		maximumIndex := start
+		for index := start; index < end; index++ {
+			area := math.Abs(
+				(times[previous]-averageTime)*(values[index]-values[previous]) -
+					(times[previous]-times[index])*(averageValue-values[previous]),
+			)
+			if area > maximumArea {
+				maximumArea = area
+				maximumIndex = index
+			}
+		}
+
+		selected = append(selected, maximumIndex)
+		previous = maximumIndex
+	}
+
+	selected = append(selected, length-1)
+
+	return selected
+}
+
+// keeps the minimum and maximum record of equally sized time buckets,
+// expects more records than the threshold, which is at least three
+func downsampleMinMax(times []float64, values []float64, threshold int) []int {
+	length := len(times)
+	buckets := threshold / 2
+
+	from := times[0]
+	width := (times[length-1] - from) / float64(buckets)
+
+	selected := make([]int, 0, threshold)
+
+	index := 0
+	for bucket := 0; bucket < buckets && index < length; bucket++ {
+		to := from + width*float64(bucket+1)
+
+		minimum := -1
+		maximum := -1
+		for index < length && (times[index] <= to || bucket == buckets-1) {
+			if minimum < 0 || values[index] < values[minimum] {
+				minimum = index
+			}
+			if maximum < 0 || values[index] > values[maximum] {
+				maximum = index
+			}
+			index++
+		}
+
+		switch {
+		case minimum < 0:
+		case minimum == maximum:
+			selected = append(selected, minimum)
+		case minimum < maximum:
+			selected = append(selected, minimum, maximum)
+		default:
+			selected = append(selected, maximum, minimum)
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestMetricDownsample(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newFrame := func() *data.Frame {
+		times := []*time.Time{}
+		values := []*float64{}
+		for index := 0; index < 1000; index++ {
+			timestamp := from.Add(time.Duration(index) * time.Second)
+			value := math.Sin(float64(index) / 10)
+			times = append(times, &timestamp)
+			values = append(values, &value)
+		}
+
+		return data.NewFrame("A",
+			data.NewField("timestamp", nil, times),
+			data.NewField("value", nil, values),
+		)
+	}
+
+	for _, method := range []string{"lttb", "minmax"} {
+		frame := newFrame()
+		frame.Meta = &data.FrameMeta{}
+		frameMeta := frame.Meta
+
+		query := queryData{
+			request:       queryRequestData{Downsample: method},
+			maxDataPoints: 100,
+		}
+
+		err := (&Datasource{}).metricDownsample(&query, frameMeta, frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		length, err := frame.RowLen()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if length == 0 || length > 100 {
+			t.Errorf("%s: unexpected length %d", method, length)
+		}
+
+		first := frame.Fields[0].At(0).(*time.Time)
+		last := frame.Fields[0].At(length - 1).(*time.Time)
+		if method == "lttb" && first.Equal(from) == false ||
+			method == "lttb" && last.Equal(from.Add(999*time.Second)) == false {
+			t.Errorf("%s: expected the first and the last record to be kept", method)
+		}
+
+		for index := 1; index < length; index++ {
+			if frame.Fields[0].At(index).(*time.Time).After(*frame.Fields[0].At(index - 1).(*time.Time)) == false {
+				t.Errorf("%s: expected time ordered records", method)
+			}
+		}
+
+		if len(frame.Meta.Notices) != 1 || len(frameMeta.Notices) != 0 {
+			t.Errorf("%s: expected a notice on the frame only", method)
+		}
+	}
+}
+
+func TestMetricDownsampleMethod(t *testing.T) {
+	frame := data.NewFrame("A",
+		data.NewField("timestamp", nil, []*time.Time{unixTime(1)}),
+		data.NewField("value", nil, []*float64{floatPointer(1)}),
+	)
+
+	// the method is checked even if the series is short enough
+	query := queryData{request: queryRequestData{Downsample: "average"}, maxDataPoints: 100}
+	err := (&Datasource{}).metricDownsample(&query, &data.FrameMeta{}, frame)
+	if err == nil {
+		t.Error("unsupported downsample method must fail")
+	}
+}
+
+func TestQueryDownsampleGroup(t *testing.T) {
+	records := []interface{}{}
+	for index := 0; index < 20; index++ {
+		for _, group := range []string{"a", "b"} {
+			records = append(records, map[string]interface{}{
+				"timestamp": time.Unix(int64(index), 0).UTC().Format(time.RFC3339),
+				"value":     math.Sin(float64(index)),
+				"host":      group,
+			})
+		}
+	}
+	ds := testDatasource(t, sqlResult("1ms", records), configuration{})
+
+	response, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID:         "A",
+			MaxDataPoints: 5,
+			JSON:          []byte(`{"mode":"metric","surql":"select * from metric","timestamp":"timestamp","metricData":"value","group":true,"groupBy":"host","downsample":"lttb"}`),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataResponse := response.Responses["A"]
+	if dataResponse.Error != nil {
+		t.Fatal(dataResponse.Error)
+	}
+	if len(dataResponse.Frames) != 2 {
+		t.Fatalf("expected a frame per group, got %d", len(dataResponse.Frames))
+	}
+	for _, frame := range dataResponse.Frames {
+		if frame.Rows() != 5 {
+			t.Errorf("%s: expected 5 downsampled rows, got %d", frame.Name, frame.Rows())
+		}
+		if frame.Meta.PreferredVisualization != data.VisTypeGraph || frame.Meta.Type != data.FrameTypeTimeSeriesMulti {
+			t.Errorf("%s: expected the graph visualization and frame type, got '%+v'", frame.Name, frame.Meta)
+		}
+		if len(frame.Meta.Notices) != 1 || frame.Meta.ExecutedQueryString == "" {
+			t.Errorf("%s: expected the downsample notice and the query meta data, got '%+v'", frame.Name, frame.Meta)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	_FAILOVER    = "failover"
+	_ROUND_ROBIN = "roundRobin"
+
+	defaultEndpointCooldown = 30 * time.Second
+)
+
+type endpoint struct {
+	location  string
+	db        transport
+	dial      *endpointDial
+	downUntil time.Time
+	lastError string
+}
+
+// a running connect of an endpoint, which concurrent queries wait for
+type endpointDial struct {
+	done chan struct{}
+	db   transport
+	err  error
+}
+
+// state of an endpoint reported by the health check
+type endpointState struct {
+	Location  string  `json:"location"`
+	Status    string  `json:"status"`
+	DownUntil string  `json:"downUntil,omitempty"`
+	LatencyMs float64 `json:"latencyMs,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// distributes the queries over several SurrealDB nodes, either in the
+// configured order (failover) or alternating (round-robin), endpoints with
+// a connection failure are skipped until their cooldown has passed
+type cluster struct {
+	config  configuration
+	options poolOptions
+
+	mutex     sync.Mutex
+	endpoints []*endpoint
+	next      int
+	closed    bool
+}
+
+// returns the configured endpoints, or the single location
+func configEndpoints(config configuration) []string {
+	if len(config.Endpoints) > 0 {
+		return config.Endpoints
+	}
+	return []string{config.Location}
+}
+
+// cuts the scheme of the endpoints, which must match the configured scheme
+func parseEndpoints(scheme string, locations []string) ([]string, error) {
+	endpoints := []string{}
+	for _, location := range locations {
+		location = strings.TrimSpace(location)
+		if location == "" {
+			continue
+		}
+		if endpointScheme, endpointLocation, found := strings.Cut(location, "://"); found {
+			if endpointScheme != scheme {
+				return nil, fmt.Errorf("endpoint '%s' uses the scheme '%s', but '%s' is configured", location, endpointScheme, scheme)
+			}
+			location = endpointLocation
+		}
+		endpoints = append(endpoints, location)
+	}
+	return endpoints, nil
+}
+
+// checks the strategy of the endpoints, the default is failover
+func checkEndpointStrategy(strategy string) error {
+	switch strategy {
+	case "", _FAILOVER, _ROUND_ROBIN:
+		return nil
+	default:
+		return fmt.Errorf("unsupported endpoint strategy '%s', expected '%s' or '%s'", strategy, _FAILOVER, _ROUND_ROBIN)
+	}
+}
+
+func newCluster(ctx context.Context, config configuration, options poolOptions) (*cluster, error) {
+	err := checkEndpointStrategy(config.EndpointStrategy)
+	if err != nil {
+		return nil, err
+	}
+	if config.EndpointCooldown <= 0 {
+		config.EndpointCooldown = defaultEndpointCooldown
+	}
+
+	c := &cluster{
+		config:  config,
+		options: options,
+	}
+	for _, location := range config.Endpoints {
+		c.endpoints = append(c.endpoints, &endpoint{location: location})
+	}
+
+	// verifies the settings with the first reachable endpoint
+	_, err = c.query(ctx, "return true")
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// returns the endpoints in the order they are tried, endpoints in their
+// cooldown are tried last, the earliest available first
+func (c *cluster) candidates(now time.Time) []*endpoint {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	start := 0
+	if c.config.EndpointStrategy == _ROUND_ROBIN {
+		start = c.next % len(c.endpoints)
+		c.next++
+	}
+
+	up := []*endpoint{}
+	down := []*endpoint{}
+	for index := range c.endpoints {
+		entry := c.endpoints[(start+index)%len(c.endpoints)]
+		if now.Before(entry.downUntil) {
+			down = append(down, entry)
+		} else {
+			up = append(up, entry)
+		}
+	}
+
+	sort.SliceStable(down, func(i, j int) bool {
+		return down[i].downUntil.Before(down[j].downUntil)
+	})
+
+	return append(up, down...)
+}
+
+// returns the transport of the endpoint, connecting on first use, the
+// connect runs without the lock, so that an unreachable endpoint does not
+// block the other endpoints, concurrent queries share the connect
+func (c *cluster) connect(ctx context.Context, entry *endpoint) (transport, error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, errClosed
+	}
+	if entry.db != nil {
+		db := entry.db
+		c.mutex.Unlock()
+		return db, nil
+	}
+	if dial := entry.dial; dial != nil {
+		c.mutex.Unlock()
+		select {
+		case <-dial.done:
+			return dial.db, dial.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	dial := &endpointDial{done: make(chan struct{})}
+	entry.dial = dial
+
+	config := c.config
+	config.Location = entry.location
+	config.Endpoints = nil
+	c.mutex.Unlock()
+
+	db, err := newEndpointTransport(ctx, config, c.options)
+
+	c.mutex.Lock()
+	entry.dial = nil
+	if err == nil && c.closed {
+		db.close()
+		db, err = nil, errClosed
+	}
+	if err == nil {
+		entry.db = db
+	}
+	c.mutex.Unlock()
+
+	dial.db = db
+	dial.err = err
+	close(dial.done)
+
+	return db, err
+}
+
+func (c *cluster) markDown(ctx context.Context, entry *endpoint, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry.downUntil = time.Now().Add(c.config.EndpointCooldown)
+	entry.lastError = err.Error()
+	if entry.db != nil {
+		// the pool waits for borrowed connections of concurrent queries
+		go entry.db.close()
+		entry.db = nil
+	}
+
+	logger(ctx).Warn("Endpoint marked down", "location", entry.location, "cooldown", c.config.EndpointCooldown, "error", err)
+}
+
+func (c *cluster) markUp(ctx context.Context, entry *endpoint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if entry.lastError != "" {
+		logger(ctx).Info("Endpoint recovered", "location", entry.location)
+	}
+	entry.downUntil = time.Time{}
+	entry.lastError = ""
+}
+
+// runs the query on the first available endpoint, a connection failure marks
+// the endpoint down and a read query is retried on the next endpoint
+func (c *cluster) query(ctx context.Context, query string) (interface{}, error) {
+	var lastErr error
+
+	for _, entry := range c.candidates(time.Now()) {
+		db, err := c.connect(ctx, entry)
+		if errors.Is(err, errClosed) {
+			return nil, err
+		}
+		if err != nil {
+			c.markDown(ctx, entry, err)
+			lastErr = err
+			continue
+		}
+
+		result, err := db.query(ctx, query)
+		if err == nil {
+			c.markUp(ctx, entry)
+			return result, nil
+		}
+		if errors.Is(err, errConnection) == false {
+			return nil, err
+		}
+
+		c.markDown(ctx, entry, err)
+		lastErr = err
+		if readOnly(query) == false {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("all %d endpoints failed: %w", len(c.endpoints), lastErr)
+}
+
+// probes every endpoint and reports its state
+func (c *cluster) states(ctx context.Context) []endpointState {
+	states := []endpointState{}
+
+	for _, entry := range c.endpoints {
+		state := endpointState{Location: entry.location, Status: "up"}
+
+		start := time.Now()
+		db, err := c.connect(ctx, entry)
+		if err == nil {
+			_, err = db.query(ctx, "return true")
+		}
+		if err != nil && errors.Is(err, errClosed) == false {
+			c.markDown(ctx, entry, err)
+		} else if err == nil {
+			c.markUp(ctx, entry)
+			state.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+		}
+
+		c.mutex.Lock()
+		if time.Now().Before(entry.downUntil) {
+			state.Status = "down"
+			state.DownUntil = entry.downUntil.UTC().Format(time.RFC3339)
+			state.Error = entry.lastError
+		}
+		c.mutex.Unlock()
+
+		states = append(states, state)
+	}
+
+	return states
+}
+
+// closes the transports without the lock, a pool waits for the connections
+// borrowed by running queries, which need the lock to mark their endpoint
+func (c *cluster) close() {
+	c.mutex.Lock()
+	c.closed = true
+	transports := []transport{}
+	for _, entry := range c.endpoints {
+		if entry.db != nil {
+			transports = append(transports, entry.db)
+			entry.db = nil
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, db := range transports {
+		db.close()
+	}
+}
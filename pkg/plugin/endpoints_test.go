@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, err := parseEndpoints(_WS, []string{"ws://node1:8000", " node2:8000 ", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(endpoints, []string{"node1:8000", "node2:8000"}) == false {
+		t.Errorf("unexpected endpoints '%v'", endpoints)
+	}
+
+	_, err = parseEndpoints(_WS, []string{"wss://node1:8000"})
+	if err == nil {
+		t.Errorf("expected a scheme mismatch error")
+	}
+}
+
+func TestEndpointStrategy(t *testing.T) {
+	tests := map[string]string{
+		"single endpoint": `{"endpointStrategy":"random","endpoints":["node1:8000"]}`,
+		"location":        `{"endpointStrategy":"random"}`,
+	}
+
+	for name, jsonData := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewDatasource(context.Background(), backend.DataSourceInstanceSettings{JSONData: []byte(jsonData)})
+			if err == nil || strings.Contains(err.Error(), "unsupported endpoint strategy") == false {
+				t.Errorf("expected an unsupported strategy error, got '%v'", err)
+			}
+		})
+	}
+}
+
+func TestCluster(t *testing.T) {
+	newNode := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			json.NewEncoder(w).Encode([]interface{}{
+				map[string]interface{}{"status": "OK", "time": "1ms", "result": name},
+			})
+		}))
+	}
+
+	node1 := newNode("node1")
+	defer node1.Close()
+	node2 := newNode("node2")
+	defer node2.Close()
+
+	ctx := context.Background()
+	options := poolOptions{maxSize: 1, idleTimeout: time.Minute}
+	config := configuration{
+		Transport:        _HTTP,
+		Scheme:           _WS,
+		Endpoints:        []string{node1.Listener.Addr().String(), node2.Listener.Addr().String()},
+		EndpointCooldown: time.Minute,
+	}
+
+	nodes := func(db transport, count int) []string {
+		result := []string{}
+		for index := 0; index < count; index++ {
+			response, err := (&Datasource{}).query(ctx, db, "return true")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result = append(result, response.result.(string))
+		}
+		return result
+	}
+
+	roundRobin := config
+	roundRobin.EndpointStrategy = _ROUND_ROBIN
+	db, err := newTransport(ctx, roundRobin, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := nodes(db, 4); reflect.DeepEqual(result, []string{"node2", "node1", "node2", "node1"}) == false {
+		t.Errorf("unexpected round-robin order '%v'", result)
+	}
+	db.close()
+
+	failover := config
+	failover.EndpointStrategy = _FAILOVER
+	db, err = newTransport(ctx, failover, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.close()
+
+	if result := nodes(db, 2); reflect.DeepEqual(result, []string{"node1", "node1"}) == false {
+		t.Errorf("unexpected failover order '%v'", result)
+	}
+
+	node1.Close()
+	if result := nodes(db, 2); reflect.DeepEqual(result, []string{"node2", "node2"}) == false {
+		t.Errorf("unexpected failover order '%v'", result)
+	}
+
+	states := db.(*cluster).states(ctx)
+	if len(states) != 2 || states[0].Status != "down" || states[0].Error == "" || states[1].Status != "up" {
+		t.Errorf("unexpected endpoint states '%+v'", states)
+	}
+
+	node2.Close()
+	_, err = db.query(ctx, "return true")
+	if err == nil {
+		t.Errorf("expected an error with all endpoints down")
+	}
+
+	_, err = newTransport(ctx, configuration{Transport: _HTTP, Scheme: _WS, Endpoints: config.Endpoints, EndpointStrategy: "random"}, options)
+	if err == nil {
+		t.Errorf("expected an unsupported strategy error")
+	}
+}
+
+func TestClusterConnect(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	requests := atomic.Int32{}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests.Add(1)
+		started <- struct{}{}
+		<-release
+		json.NewEncoder(w).Encode([]interface{}{
+			map[string]interface{}{"status": "OK", "time": "1ms", "result": true},
+		})
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode([]interface{}{
+			map[string]interface{}{"status": "OK", "time": "1ms", "result": true},
+		})
+	}))
+	defer fast.Close()
+
+	c := &cluster{
+		config:  configuration{Transport: _HTTP, Scheme: _WS, EndpointCooldown: time.Minute},
+		options: poolOptions{maxSize: 1, idleTimeout: time.Minute},
+		endpoints: []*endpoint{
+			{location: slow.Listener.Addr().String()},
+			{location: fast.Listener.Addr().String()},
+		},
+	}
+	defer c.close()
+
+	ctx := context.Background()
+
+	type result struct {
+		db  transport
+		err error
+	}
+	results := make(chan result, 2)
+	for index := 0; index < 2; index++ {
+		go func() {
+			db, err := c.connect(ctx, c.endpoints[0])
+			results <- result{db, err}
+		}()
+	}
+	<-started
+
+	// the other endpoint is usable while the first one is connecting
+	done := make(chan error)
+	go func() {
+		c.candidates(time.Now())
+		_, err := c.connect(ctx, c.endpoints[1])
+		c.markUp(ctx, c.endpoints[1])
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the cluster is blocked by the connecting endpoint")
+	}
+
+	release <- struct{}{}
+	first := <-results
+	second := <-results
+	if first.err != nil || second.err != nil {
+		t.Fatalf("unexpected errors '%v', '%v'", first.err, second.err)
+	}
+	if first.db != second.db {
+		t.Errorf("expected the concurrent connects to share the transport")
+	}
+	if count := requests.Load(); count != 1 {
+		t.Errorf("expected a single connect, got %d", count)
+	}
+}
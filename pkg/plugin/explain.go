@@ -0,0 +1,195 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	explainStrategyIndex     = "index"
+	explainStrategyTableScan = "table scan"
+	explainStrategyRecord    = "record"
+	explainStrategyCollector = "collector"
+	explainStrategyFetch     = "fetch"
+)
+
+var explainSuffix = regexp.MustCompile(`(?i)\bexplain(\s+full)?$`)
+
+// statements which may precede the explained SELECT statement, they are
+// executed, so they must not change data
+var explainPreceding = map[string]bool{
+	"LET":    true,
+	"SELECT": true,
+	"USE":    true,
+}
+
+// appends the 'EXPLAIN' or 'EXPLAIN FULL' clause to the last statement, which
+// must be a SELECT statement, 'EXPLAIN FULL' executes the statement to count
+// the fetched records, but the records are not returned, the statements
+// before it run for real and are restricted to LET, SELECT and USE
+// https://surrealdb.com/docs/surrealdb/surrealql/statements/select#the-explain-clause
+func explainQuery(surql string, full bool) (string, error) {
+	statements := splitStatements(surql)
+	if len(statements) == 0 {
+		return "", fmt.Errorf("explain requires a SELECT statement")
+	}
+
+	for _, entry := range statements[:len(statements)-1] {
+		if explainPreceding[entry.keyword()] == false || entry.writes() {
+			return "", fmt.Errorf("explain would execute '%s', only LET, SELECT and USE statements without writes can precede the explained SELECT", entry.text)
+		}
+	}
+
+	last := statements[len(statements)-1]
+	if last.keyword() != "SELECT" {
+		return "", fmt.Errorf("explain requires a SELECT statement, got '%s'", last.text)
+	}
+	if last.writes() {
+		return "", fmt.Errorf("explain would execute the writes of '%s'", last.text)
+	}
+
+	surql = joinStatements(statements)
+	if explainSuffix.MatchString(last.text) {
+		return surql, nil
+	}
+
+	if full {
+		return surql + " EXPLAIN FULL", nil
+	}
+	return surql + " EXPLAIN", nil
+}
+
+// returns the iterator strategy of an explained operation
+func explainStrategy(operation string) string {
+	switch {
+	case strings.HasSuffix(operation, "Index"):
+		return explainStrategyIndex
+	case operation == "Iterate Table":
+		return explainStrategyTableScan
+	case strings.HasPrefix(operation, "Iterate"):
+		return explainStrategyRecord
+	case operation == "Collector":
+		return explainStrategyCollector
+	case operation == "Fetch":
+		return explainStrategyFetch
+	default:
+		return strings.ToLower(operation)
+	}
+}
+
+// converts the plan into a table with one row per operation, the strategy is
+// colored and a table scan is reported as a notice
+func (r *Datasource) explain(query *queryData, frameMeta *data.FrameMeta, dataResponse *backend.DataResponse) error {
+	operations, isArray := query.response.result.([]interface{})
+	if isArray == false {
+		return fmt.Errorf("explain returned '%v' instead of an array", query.response.result)
+	}
+
+	operationField := []string{}
+	strategyField := []string{}
+	tableField := []string{}
+	indexField := []*string{}
+	operatorField := []*string{}
+	valueField := []*string{}
+	countField := []*float64{}
+	detailField := []*string{}
+
+	notices := []data.Notice{}
+
+	for _, entry := range operations {
+		step, isMap := entry.(map[string]interface{})
+		if isMap == false {
+			return fmt.Errorf("explain returned the invalid operation '%v'", entry)
+		}
+
+		operation := fmt.Sprintf("%v", step["operation"])
+		detail, _ := step["detail"].(map[string]interface{})
+		plan, _ := detail["plan"].(map[string]interface{})
+
+		strategy := explainStrategy(operation)
+		table, _ := detail["table"].(string)
+
+		operationField = append(operationField, operation)
+		strategyField = append(strategyField, strategy)
+		tableField = append(tableField, table)
+		indexField = append(indexField, explainString(plan["index"]))
+		operatorField = append(operatorField, explainString(plan["operator"]))
+		valueField = append(valueField, explainJSON(plan["value"]))
+		detailField = append(detailField, explainJSON(detail))
+
+		if count, isCount := detail["count"].(float64); isCount {
+			countField = append(countField, &count)
+		} else {
+			countField = append(countField, nil)
+		}
+
+		if strategy == explainStrategyTableScan {
+			notices = append(notices, data.Notice{
+				Severity: data.NoticeSeverityWarning,
+				Text:     fmt.Sprintf("The query iterates the table '%s' without an index", table),
+			})
+		}
+	}
+
+	strategy := data.NewField("strategy", nil, strategyField)
+	strategy.Config = &data.FieldConfig{
+		Mappings: data.ValueMappings{
+			data.ValueMapper{
+				explainStrategyIndex:     {Color: "green", Index: 0},
+				explainStrategyRecord:    {Color: "green", Index: 1},
+				explainStrategyTableScan: {Color: "red", Index: 2},
+				explainStrategyCollector: {Color: "blue", Index: 3},
+				explainStrategyFetch:     {Color: "blue", Index: 4},
+			},
+		},
+		Custom: map[string]interface{}{
+			"cellOptions": map[string]interface{}{"type": "color-background"},
+		},
+	}
+
+	frame := data.NewFrame(
+		query.name,
+		data.NewField("operation", nil, operationField),
+		strategy,
+		data.NewField("table", nil, tableField),
+		data.NewField("index", nil, indexField),
+		data.NewField("operator", nil, operatorField),
+		data.NewField("value", nil, valueField),
+		data.NewField("count", nil, countField),
+		data.NewField("detail", nil, detailField),
+	)
+
+	meta := *frameMeta
+	meta.PreferredVisualization = data.VisTypeTable
+	meta.Notices = append([]data.Notice{}, notices...)
+	frame.Meta = &meta
+
+	dataResponse.Frames = append(dataResponse.Frames, frame)
+
+	return nil
+}
+
+func explainString(value interface{}) *string {
+	if value == nil {
+		return nil
+	}
+	result := fmt.Sprintf("%v", value)
+	return &result
+}
+
+func explainJSON(value interface{}) *string {
+	if value == nil {
+		return nil
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return explainString(value)
+	}
+	result := string(bytes)
+	return &result
+}
@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestExplainQuery(t *testing.T) {
+	tests := []struct {
+		surql    string
+		full     bool
+		expected string
+		invalid  bool
+	}{
+		{"select * from person where email = 'a@b.c'", false, "select * from person where email = 'a@b.c' EXPLAIN", false},
+		{"select * from person;\n", true, "select * from person EXPLAIN FULL", false},
+		{"let $x = 1; SELECT * FROM person WHERE age > $x", false, "let $x = 1; SELECT * FROM person WHERE age > $x EXPLAIN", false},
+		{"select * from person explain full", false, "select * from person explain full", false},
+		{"delete person", false, "", true},
+		{"select * from person; delete person", false, "", true},
+		{"DELETE person; SELECT * FROM person", false, "", true},
+		{"let $x = (delete person return before); select * from person", false, "", true},
+		{"update person set age = 1; select * from person", false, "", true},
+		{"select * from (delete person)", false, "", true},
+		{"use db test; select * from person where name = 'a;b' -- last", false, "use db test; select * from person where name = 'a;b' EXPLAIN", false},
+		{"select * from log where msg = \"delete; b\"", true, "select * from log where msg = \"delete; b\" EXPLAIN FULL", false},
+		{"", false, "", true},
+	}
+
+	for _, test := range tests {
+		result, err := explainQuery(test.surql, test.full)
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected error '%v'", test.surql, err)
+		}
+		if result != test.expected {
+			t.Errorf("%s: expected '%s', got '%s'", test.surql, test.expected, result)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	query := queryData{
+		name: "A",
+		response: queryResponseData{
+			result: []interface{}{
+				map[string]interface{}{
+					"operation": "Iterate Index",
+					"detail": map[string]interface{}{
+						"table": "person",
+						"plan":  map[string]interface{}{"index": "email", "operator": "=", "value": "a@b.c"},
+					},
+				},
+				map[string]interface{}{
+					"operation": "Iterate Table",
+					"detail":    map[string]interface{}{"table": "log"},
+				},
+				map[string]interface{}{
+					"operation": "Collector",
+					"detail":    map[string]interface{}{"type": "Memory"},
+				},
+				map[string]interface{}{
+					"operation": "Fetch",
+					"detail":    map[string]interface{}{"count": float64(3)},
+				},
+			},
+		},
+	}
+
+	ds := Datasource{}
+	frameMeta := &data.FrameMeta{ExecutedQueryString: "select * from person EXPLAIN FULL"}
+
+	var dataResponse backend.DataResponse
+	err := ds.explain(&query, frameMeta, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := dataResponse.Frames[0]
+	if frame.Rows() != 4 {
+		t.Fatalf("expected 4 operations, got %d", frame.Rows())
+	}
+
+	strategies := []string{explainStrategyIndex, explainStrategyTableScan, explainStrategyCollector, explainStrategyFetch}
+	for index, strategy := range strategies {
+		if value := frame.Fields[1].At(index); value != strategy {
+			t.Errorf("row %d: expected strategy '%s', got '%v'", index, strategy, value)
+		}
+	}
+
+	if index := frame.Fields[3].At(0).(*string); index == nil || *index != "email" {
+		t.Errorf("expected the index 'email', got '%v'", index)
+	}
+	if count := frame.Fields[6].At(3).(*float64); count == nil || *count != 3 {
+		t.Errorf("expected the fetch count 3, got '%v'", count)
+	}
+
+	if len(frame.Meta.Notices) != 1 || frame.Meta.ExecutedQueryString != frameMeta.ExecutedQueryString {
+		t.Errorf("unexpected metadata '%+v'", frame.Meta)
+	}
+	if len(frameMeta.Notices) != 0 {
+		t.Errorf("expected the shared metadata to be unchanged")
+	}
+
+	query.response.result = "invalid"
+	err = ds.explain(&query, frameMeta, &backend.DataResponse{})
+	if err == nil {
+		t.Errorf("expected an error for an invalid plan")
+	}
+}
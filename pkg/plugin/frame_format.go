@@ -0,0 +1,271 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	_MULTI = "multi"
+	_WIDE  = "wide"
+	_LONG  = "long"
+)
+
+// https://grafana.com/developers/dataplane/timeseries
+var frameFormatTypeVersion = data.FrameTypeVersion{0, 1}
+
+// converts the series frames of a metric query into the requested format, by
+// default each series is a frame (multi), the long format has one frame with
+// the group or the labels as string columns and the wide format one frame
+// with them as labels of the value fields, the frame type is set for every
+// format
+func (r *Datasource) metricFrameFormat(query *queryData, frameMeta *data.FrameMeta, dataResponse *backend.DataResponse) error {
+	switch query.request.FrameFormat {
+	case "", _MULTI:
+		for _, frame := range dataResponse.Frames {
+			meta := *frameMeta
+			if frame.Meta != nil {
+				meta = *frame.Meta
+			}
+			meta.Type = data.FrameTypeTimeSeriesMulti
+			meta.TypeVersion = frameFormatTypeVersion
+			frame.Meta = &meta
+		}
+		return nil
+	case _LONG, _WIDE:
+	default:
+		return fmt.Errorf("unsupported frame format '%s', expected '%s', '%s' or '%s'", query.request.FrameFormat, _MULTI, _WIDE, _LONG)
+	}
+
+	frame, err := metricLong(query, dataResponse.Frames)
+	if err != nil {
+		return err
+	}
+
+	meta := *frameMeta
+	meta.Type = data.FrameTypeTimeSeriesLong
+	meta.TypeVersion = frameFormatTypeVersion
+
+	if query.request.FrameFormat == _WIDE {
+		frame, err = metricWide(frame)
+		if err != nil {
+			return err
+		}
+		meta.Type = data.FrameTypeTimeSeriesWide
+	}
+	frame.Meta = &meta
+
+	dataResponse.Frames = data.Frames{frame}
+
+	return nil
+}
+
+// splits the rows of the metric frame into a series per distinct value of the
+// string fields, which become the labels of the value fields, the numeric
+// fields are kept as further values, the series are named by their labels in
+// the order of their first row, fields of other types are dropped
+func metricSeries(frame *data.Frame, fields []*data.Field) data.Frames {
+	labelFields := []*data.Field{}
+	for _, field := range fields {
+		switch {
+		case field.Type() == data.FieldTypeString || field.Type() == data.FieldTypeNullableString:
+			labelFields = append(labelFields, field)
+		case field.Type().Numeric():
+			frame.Fields = append(frame.Fields, field)
+		}
+	}
+	if len(labelFields) == 0 {
+		return data.Frames{frame}
+	}
+
+	keys := []string{}
+	labels := map[string]data.Labels{}
+	rows := map[string][]int{}
+	for index := 0; index < frame.Fields[0].Len(); index++ {
+		rowLabels := data.Labels{}
+		for _, field := range labelFields {
+			value, isValue := field.ConcreteAt(index)
+			if isValue == false {
+				value = ""
+			}
+			rowLabels[field.Name] = value.(string)
+		}
+
+		key := rowLabels.String()
+		if _, exists := labels[key]; exists == false {
+			keys = append(keys, key)
+			labels[key] = rowLabels
+		}
+		rows[key] = append(rows[key], index)
+	}
+
+	series := data.Frames{}
+	for _, key := range keys {
+		seriesFrame := data.NewFrame(key)
+		for fieldIndex, field := range frame.Fields {
+			seriesField := data.NewFieldFromFieldType(field.Type(), len(rows[key]))
+			seriesField.Name = field.Name
+			seriesField.Config = field.Config
+			if fieldIndex > 0 {
+				seriesField.Labels = labels[key].Copy()
+			}
+			for position, index := range rows[key] {
+				seriesField.Set(position, field.CopyAt(index))
+			}
+			seriesFrame.Fields = append(seriesFrame.Fields, seriesField)
+		}
+		series = append(series, seriesFrame)
+	}
+
+	return series
+}
+
+// merges the series frames into one frame sorted by time, each series is a
+// wide frame converted with 'data.WideToLong', so that the group of a series
+// and the labels of its values become string columns, rows without time are
+// dropped, the series must have distinct names, otherwise their rows could
+// not be told apart
+func metricLong(query *queryData, frames data.Frames) (*data.Frame, error) {
+	long := data.NewFrame(query.name)
+	if len(frames) == 0 {
+		long.Fields = append(long.Fields, data.NewField(query.request.Timestamp, nil, []*time.Time{}))
+		return long, nil
+	}
+
+	first := frames[0]
+	names := map[string]bool{}
+	for _, frame := range frames {
+		if names[frame.Name] {
+			return nil, fmt.Errorf("series '%s' occurs more than once", frame.Name)
+		}
+		names[frame.Name] = true
+
+		if len(frame.Fields) != len(first.Fields) {
+			return nil, fmt.Errorf("series '%s' has %d fields instead of %d", frame.Name, len(frame.Fields), len(first.Fields))
+		}
+		for index, field := range frame.Fields {
+			if field.Name != first.Fields[index].Name || field.Type() != first.Fields[index].Type() {
+				return nil, fmt.Errorf("series '%s' has the field '%s' instead of '%s'", frame.Name, field.Name, first.Fields[index].Name)
+			}
+		}
+	}
+
+	type row struct {
+		frame *data.Frame
+		index int
+		time  time.Time
+	}
+
+	rows := []row{}
+	labelNames := []string{}
+	for _, frame := range frames {
+		wide := metricSeriesWide(query, frame)
+		if wide.Rows() == 0 {
+			continue
+		}
+
+		converted, err := data.WideToLong(wide)
+		if err != nil {
+			return nil, fmt.Errorf("series '%s': %w", frame.Name, err)
+		}
+
+		for _, field := range converted.Fields[1:] {
+			if field.Type() == data.FieldTypeString && contains(labelNames, field.Name) == false {
+				labelNames = append(labelNames, field.Name)
+			}
+		}
+		for index := 0; index < converted.Rows(); index++ {
+			rows = append(rows, row{converted, index, converted.Fields[0].At(index).(time.Time)})
+		}
+	}
+	sort.Strings(labelNames)
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].time.Before(rows[j].time)
+	})
+
+	// time, labels and values in the order of the series
+	timeField := data.NewField(first.Fields[0].Name, nil, make([]time.Time, len(rows)))
+	long.Fields = append(long.Fields, timeField)
+
+	labelFields := []*data.Field{}
+	for _, name := range labelNames {
+		labelField := data.NewField(name, nil, make([]string, len(rows)))
+		labelFields = append(labelFields, labelField)
+		long.Fields = append(long.Fields, labelField)
+	}
+
+	valueFields := []*data.Field{}
+	for _, field := range first.Fields[1:] {
+		valueField := data.NewFieldFromFieldType(field.Type(), len(rows))
+		valueField.Name = field.Name
+		valueField.Config = field.Config
+		valueFields = append(valueFields, valueField)
+		long.Fields = append(long.Fields, valueField)
+	}
+
+	for index, entry := range rows {
+		timeField.Set(index, entry.time)
+		for _, labelField := range labelFields {
+			if field, _ := entry.frame.FieldByName(labelField.Name); field != nil {
+				labelField.Set(index, field.At(entry.index))
+			}
+		}
+		for _, valueField := range valueFields {
+			field, _ := entry.frame.FieldByName(valueField.Name)
+			valueField.Set(index, field.CopyAt(entry.index))
+		}
+	}
+
+	return long, nil
+}
+
+// returns the rows with time of the series as wide frame, the group of a
+// grouped series is set as label of its values
+func metricSeriesWide(query *queryData, frame *data.Frame) *data.Frame {
+	timeField := frame.Fields[0] // see 'metric()'
+
+	rows := []int{}
+	for index := 0; index < timeField.Len(); index++ {
+		if _, isTime := timeField.ConcreteAt(index); isTime {
+			rows = append(rows, index)
+		}
+	}
+
+	wide := data.NewFrame(frame.Name)
+	for fieldIndex, field := range frame.Fields {
+		wideField := data.NewFieldFromFieldType(field.Type(), len(rows))
+		wideField.Name = field.Name
+		if fieldIndex > 0 {
+			wideField.Labels = field.Labels.Copy()
+			if query.request.Group {
+				wideField.Labels[query.request.GroupBy] = frame.Name
+			}
+		}
+		for position, index := range rows {
+			wideField.Set(position, field.CopyAt(index))
+		}
+		wide.Fields = append(wide.Fields, wideField)
+	}
+
+	return wide
+}
+
+// converts the long frame with 'data.LongToWide', a frame without group
+// column or rows is already wide
+func metricWide(long *data.Frame) (*data.Frame, error) {
+	if long.TimeSeriesSchema().Type != data.TimeSeriesTypeLong || long.Rows() == 0 {
+		wide := data.NewFrame(long.Name)
+		for _, field := range long.Fields {
+			if field.Type() != data.FieldTypeString {
+				wide.Fields = append(wide.Fields, field)
+			}
+		}
+		return wide, nil
+	}
+
+	return data.LongToWide(long, nil)
+}
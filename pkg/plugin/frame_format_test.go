@@ -0,0 +1,272 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func frameFormatTestSeries() data.Frames {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	t2 := t0.Add(2 * time.Minute)
+	v := func(value float64) *float64 { return &value }
+
+	return data.Frames{
+		data.NewFrame("a",
+			data.NewField("timestamp", nil, []*time.Time{&t0, &t2}),
+			data.NewField("value", nil, []*float64{v(1), v(3)}),
+		),
+		data.NewFrame("b",
+			data.NewField("timestamp", nil, []*time.Time{&t1, nil}),
+			data.NewField("value", nil, []*float64{v(2), v(4)}),
+		),
+	}
+}
+
+func TestMetricFrameFormatMulti(t *testing.T) {
+	query := queryData{name: "A", request: queryRequestData{Group: true, GroupBy: "host"}}
+	frameMeta := &data.FrameMeta{ExecutedQueryString: "select * from metric"}
+
+	dataResponse := backend.DataResponse{Frames: frameFormatTestSeries()}
+	err := (&Datasource{}).metricFrameFormat(&query, frameMeta, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dataResponse.Frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(dataResponse.Frames))
+	}
+	for _, frame := range dataResponse.Frames {
+		if frame.Meta.Type != data.FrameTypeTimeSeriesMulti || frame.Meta.ExecutedQueryString != frameMeta.ExecutedQueryString {
+			t.Errorf("%s: unexpected meta '%v'", frame.Name, frame.Meta)
+		}
+	}
+	if frameMeta.Type != "" {
+		t.Errorf("expected the shared frame meta to be unchanged")
+	}
+}
+
+func TestMetricFrameFormatLong(t *testing.T) {
+	query := queryData{name: "A", request: queryRequestData{Group: true, GroupBy: "host", FrameFormat: _LONG}}
+
+	dataResponse := backend.DataResponse{Frames: frameFormatTestSeries()}
+	err := (&Datasource{}).metricFrameFormat(&query, &data.FrameMeta{}, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dataResponse.Frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(dataResponse.Frames))
+	}
+	frame := dataResponse.Frames[0]
+	if frame.Meta.Type != data.FrameTypeTimeSeriesLong {
+		t.Errorf("expected type '%s', got '%s'", data.FrameTypeTimeSeriesLong, frame.Meta.Type)
+	}
+	if schema := frame.TimeSeriesSchema(); schema.Type != data.TimeSeriesTypeLong {
+		t.Errorf("expected a long series, got '%s'", schema.Type)
+	}
+
+	// sorted by time, the row without time is dropped
+	expected := []struct {
+		host  string
+		value float64
+	}{
+		{"a", 1},
+		{"b", 2},
+		{"a", 3},
+	}
+	if frame.Rows() != len(expected) {
+		t.Fatalf("expected %d rows, got %d", len(expected), frame.Rows())
+	}
+	for index, row := range expected {
+		if host := frame.Fields[1].At(index); host != row.host {
+			t.Errorf("%d: expected host '%s', got '%v'", index, row.host, host)
+		}
+		if value := frame.Fields[2].At(index).(*float64); *value != row.value {
+			t.Errorf("%d: expected value %v, got %v", index, row.value, *value)
+		}
+	}
+}
+
+func TestMetricFrameFormatWide(t *testing.T) {
+	query := queryData{name: "A", request: queryRequestData{Group: true, GroupBy: "host", FrameFormat: _WIDE}}
+
+	dataResponse := backend.DataResponse{Frames: frameFormatTestSeries()}
+	err := (&Datasource{}).metricFrameFormat(&query, &data.FrameMeta{}, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := dataResponse.Frames[0]
+	if frame.Meta.Type != data.FrameTypeTimeSeriesWide {
+		t.Errorf("expected type '%s', got '%s'", data.FrameTypeTimeSeriesWide, frame.Meta.Type)
+	}
+	if len(frame.Fields) != 3 || frame.Rows() != 3 {
+		t.Fatalf("expected 3 fields and 3 rows, got %d and %d", len(frame.Fields), frame.Rows())
+	}
+	for index, host := range []string{"a", "b"} {
+		if labels := frame.Fields[index+1].Labels; labels["host"] != host {
+			t.Errorf("expected the label host '%s', got '%v'", host, labels)
+		}
+	}
+
+	// without group the single series is already wide
+	query.request.Group = false
+	dataResponse = backend.DataResponse{Frames: frameFormatTestSeries()[:1]}
+	err = (&Datasource{}).metricFrameFormat(&query, &data.FrameMeta{}, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame = dataResponse.Frames[0]
+	if len(frame.Fields) != 2 || frame.Rows() != 2 || frame.Meta.Type != data.FrameTypeTimeSeriesWide {
+		t.Errorf("unexpected wide frame with %d fields and %d rows", len(frame.Fields), frame.Rows())
+	}
+
+	// an empty result is an empty wide frame
+	dataResponse = backend.DataResponse{Frames: data.Frames{}}
+	err = (&Datasource{}).metricFrameFormat(&query, &data.FrameMeta{}, &dataResponse)
+	if err != nil || len(dataResponse.Frames) != 1 || dataResponse.Frames[0].Rows() != 0 {
+		t.Errorf("expected an empty frame, got %v (%v)", dataResponse.Frames, err)
+	}
+}
+
+func TestMetricSeries(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	region := "eu"
+
+	frame := data.NewFrame("A",
+		data.NewField("timestamp", nil, []*time.Time{&t0, &t0, &t0}),
+		data.NewField("value", nil, []*float64{floatPointer(1), floatPointer(2), floatPointer(3)}),
+	)
+	series := metricSeries(frame, []*data.Field{
+		data.NewField("host", nil, []string{"b", "a", "b"}),
+		data.NewField("region", nil, []*string{&region, nil, &region}),
+		data.NewField("memory", nil, []*float64{floatPointer(10), floatPointer(20), floatPointer(30)}),
+		data.NewField("up", nil, []bool{true, false, true}),
+	})
+
+	// in the order of the first row, the bool field is dropped
+	if len(series) != 2 || series[0].Name != "host=b, region=eu" || series[1].Name != "host=a, region=" {
+		t.Fatalf("unexpected series '%v'", series)
+	}
+	for _, frame := range series {
+		names := []string{}
+		for _, field := range frame.Fields {
+			names = append(names, field.Name)
+		}
+		if reflect.DeepEqual(names, []string{"timestamp", "value", "memory"}) == false {
+			t.Errorf("%s: unexpected fields '%v'", frame.Name, names)
+		}
+		if frame.Fields[0].Labels != nil || frame.Fields[2].Labels["host"] == "" {
+			t.Errorf("%s: expected the labels on the value fields", frame.Name)
+		}
+	}
+	if series[0].Rows() != 2 || *series[0].Fields[2].At(1).(*float64) != 30 {
+		t.Errorf("expected the rows of the series, got '%v'", series[0])
+	}
+
+	// without string fields the frame is a single series
+	frame = data.NewFrame("A", data.NewField("timestamp", nil, []*time.Time{&t0}), data.NewField("value", nil, []*float64{floatPointer(1)}))
+	series = metricSeries(frame, []*data.Field{data.NewField("memory", nil, []*float64{floatPointer(10)})})
+	if len(series) != 1 || len(series[0].Fields) != 3 || series[0].Fields[1].Labels != nil {
+		t.Errorf("expected a single series with the extra value, got '%v'", series)
+	}
+}
+
+func TestQueryFrameFormatLabels(t *testing.T) {
+	records := []interface{}{}
+	for index, host := range []string{"a", "b", "a", "b"} {
+		records = append(records, map[string]interface{}{
+			"timestamp": time.Unix(int64(index/2), 0).UTC().Format(time.RFC3339),
+			"host":      host,
+			"value":     index,
+			"memory":    index * 10,
+		})
+	}
+	ds := testDatasource(t, sqlResult("1ms", records), configuration{})
+
+	query := func(format string, options string) *data.Frame {
+		response, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+			Queries: []backend.DataQuery{{
+				RefID:     "A",
+				TimeRange: backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(2, 0)},
+				Interval:  time.Second,
+				JSON:      []byte(`{"mode":"metric","surql":"select * from metric","timestamp":"timestamp","metricData":"value","frameFormat":"` + format + `"` + options + `}`),
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		dataResponse := response.Responses["A"]
+		if dataResponse.Error != nil {
+			t.Fatal(dataResponse.Error)
+		}
+		if len(dataResponse.Frames) != 1 {
+			t.Fatalf("%s: expected a single frame, got %d", format, len(dataResponse.Frames))
+		}
+		return dataResponse.Frames[0]
+	}
+
+	long := query(_LONG, "")
+	names := []string{}
+	for _, field := range long.Fields {
+		names = append(names, field.Name)
+	}
+	if reflect.DeepEqual(names, []string{"timestamp", "host", "value", "memory"}) == false || long.Rows() != 4 {
+		t.Errorf("expected the host column and the memory values, got '%v' with %d rows", names, long.Rows())
+	}
+	if long.Meta.Type != data.FrameTypeTimeSeriesLong {
+		t.Errorf("expected type '%s', got '%s'", data.FrameTypeTimeSeriesLong, long.Meta.Type)
+	}
+
+	wide := query(_WIDE, "")
+	if wide.Meta.Type != data.FrameTypeTimeSeriesWide || len(wide.Fields) != 5 || wide.Rows() != 2 {
+		t.Fatalf("expected a wide frame with a value and memory field per host, got '%v'", wide)
+	}
+	for _, field := range wide.Fields[1:] {
+		if field.Labels["host"] != "a" && field.Labels["host"] != "b" {
+			t.Errorf("%s: expected the host label, got '%v'", field.Name, field.Labels)
+		}
+	}
+
+	// the rate of every series keeps its labels
+	rate := query(_LONG, `,"rate":true,"rateInterval":"1s","rateFunctions":["sum"]`)
+	if host, _ := rate.FieldByName("host"); host == nil || rate.Rows() == 0 {
+		t.Errorf("expected the host column of the rates, got '%v'", rate)
+	}
+}
+
+func TestMetricFrameFormatInvalid(t *testing.T) {
+	query := queryData{name: "A", request: queryRequestData{FrameFormat: "matrix"}}
+
+	dataResponse := backend.DataResponse{Frames: frameFormatTestSeries()}
+	if err := (&Datasource{}).metricFrameFormat(&query, &data.FrameMeta{}, &dataResponse); err == nil {
+		t.Errorf("expected an unsupported frame format")
+	}
+
+	series := frameFormatTestSeries()
+	series[1].Fields[1].Name = "count"
+	query.request.FrameFormat = _LONG
+	dataResponse = backend.DataResponse{Frames: series}
+	if err := (&Datasource{}).metricFrameFormat(&query, &data.FrameMeta{}, &dataResponse); err == nil {
+		t.Errorf("expected an error for series with different fields")
+	}
+
+	// e.g. a group named like the folded other series, see 'metricGroup()'
+	for _, format := range []string{_LONG, _WIDE} {
+		series = frameFormatTestSeries()
+		series[1].Name = series[0].Name
+		query.request.FrameFormat = format
+		dataResponse = backend.DataResponse{Frames: series}
+		err := (&Datasource{}).metricFrameFormat(&query, &data.FrameMeta{}, &dataResponse)
+		if err == nil || strings.Contains(err.Error(), "more than once") == false {
+			t.Errorf("%s: expected an error for duplicate series, got '%v'", format, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// version of the 'FrameMeta.Custom' payload, increased on incompatible changes
+const frameMetaCustomVersion = 1
+
+// the custom frame metadata, the first four fields are kept from the
+// unversioned payload
+type frameMetaCustom struct {
+	Version  int    `json:"version"`
+	QueryRaw string `json:"queryRaw"`
+	QueryRun string `json:"queryRun"`
+	Status   string `json:"status"`
+	Time     string `json:"time"`
+
+	ServerTimeMs *float64 `json:"serverTimeMs,omitempty"`
+
+	// the cursor of the next page of a paginated query
+	Cursor string `json:"cursor,omitempty"`
+}
+
+func newFrameMetaCustom(queryRaw string, queryRun string, response queryResponseData) frameMetaCustom {
+	custom := frameMetaCustom{
+		Version:  frameMetaCustomVersion,
+		QueryRaw: queryRaw,
+		QueryRun: queryRun,
+		Status:   fmt.Sprintf("%v", response.status),
+		Time:     fmt.Sprintf("%v", response.time),
+	}
+
+	serverTime, err := parseServerTime(response.time)
+	if err == nil {
+		serverTimeMs := durationMs(serverTime)
+		custom.ServerTimeMs = &serverTimeMs
+	}
+
+	return custom
+}
+
+// parses the statement time reported by SurrealDB, e.g. '1.234567ms' or '12µs'
+func parseServerTime(value interface{}) (time.Duration, error) {
+	switch serverTime := value.(type) {
+	case time.Duration:
+		return serverTime, nil
+	case string:
+		return time.ParseDuration(serverTime)
+	default:
+		return 0, fmt.Errorf("invalid server time '%v'", value)
+	}
+}
+
+func durationMs(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+type responseSizeKey struct{}
+
+// returns a context which counts the bytes of the query responses received by
+// the transports, see 'recordResponseSize()'
+func withResponseSize(ctx context.Context) (context.Context, *atomic.Int64) {
+	size := &atomic.Int64{}
+	return context.WithValue(ctx, responseSizeKey{}, size), size
+}
+
+// adds the length of a received query response message to the size counter
+// of the context, if there is one
+func recordResponseSize(ctx context.Context, size int) {
+	counter, exists := ctx.Value(responseSizeKey{}).(*atomic.Int64)
+	if exists {
+		counter.Add(int64(size))
+	}
+}
+
+// sets the executed query and the statistics of the query on every frame,
+// frames which share their metadata get their own copy
+func frameStats(frames data.Frames, frameMeta *data.FrameMeta, response queryResponseData, processing time.Duration) {
+	serverTime, serverTimeErr := parseServerTime(response.time)
+
+	for _, frame := range frames {
+		meta := *frameMeta
+		if frame.Meta != nil {
+			meta = *frame.Meta
+		}
+
+		if meta.ExecutedQueryString == "" {
+			meta.ExecutedQueryString = frameMeta.ExecutedQueryString
+		}
+		if meta.Custom == nil {
+			meta.Custom = frameMeta.Custom
+		}
+
+		meta.Stats = []data.QueryStat{}
+		if serverTimeErr == nil {
+			meta.Stats = append(meta.Stats, queryStat("Server time", "ms", durationMs(serverTime)))
+		}
+		meta.Stats = append(meta.Stats,
+			queryStat("Rows", "none", float64(frame.Rows())),
+			queryStat("Response size", "decbytes", float64(response.size)),
+			queryStat("Processing time", "ms", durationMs(processing)),
+		)
+
+		frame.Meta = &meta
+	}
+}
+
+// https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/data#QueryStat
+func queryStat(name string, unit string, value float64) data.QueryStat {
+	return data.QueryStat{
+		FieldConfig: data.FieldConfig{
+			DisplayName: name,
+			Unit:        unit,
+		},
+		Value: value,
+	}
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestParseServerTime(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected time.Duration
+		invalid  bool
+	}{
+		{"1.5ms", 1500 * time.Microsecond, false},
+		{"12µs", 12 * time.Microsecond, false},
+		{"2s", 2 * time.Second, false},
+		{time.Millisecond, time.Millisecond, false},
+		{"fast", 0, true},
+		{nil, 0, true},
+	}
+
+	for _, test := range tests {
+		result, err := parseServerTime(test.value)
+		if (err != nil) != test.invalid {
+			t.Errorf("%v: unexpected error '%v'", test.value, err)
+		}
+		if result != test.expected {
+			t.Errorf("%v: expected '%v', got '%v'", test.value, test.expected, result)
+		}
+	}
+}
+
+func TestFrameStats(t *testing.T) {
+	response := queryResponseData{
+		status: "OK",
+		time:   "2ms",
+		result: []interface{}{map[string]interface{}{"value": 1}},
+		size:   1234,
+	}
+
+	frameMeta := &data.FrameMeta{
+		PreferredVisualization: data.VisTypeTable,
+		ExecutedQueryString:    "select * from metric",
+		Custom:                 newFrameMetaCustom("select * from $table", "select * from metric", response),
+	}
+
+	shared := data.NewFrame("A", data.NewField("value", nil, []float64{1}))
+	shared.Meta = frameMeta
+	detached := data.NewFrame("B", data.NewField("value", nil, []float64{1, 2}))
+
+	frameStats(data.Frames{shared, detached}, frameMeta, response, 500*time.Microsecond)
+
+	if frameMeta.Stats != nil {
+		t.Errorf("expected the shared metadata to be copied")
+	}
+
+	for _, frame := range []*data.Frame{shared, detached} {
+		if frame.Meta == nil || frame.Meta.ExecutedQueryString != "select * from metric" {
+			t.Fatalf("%s: unexpected metadata '%+v'", frame.Name, frame.Meta)
+		}
+
+		stats := map[string]float64{}
+		for _, stat := range frame.Meta.Stats {
+			stats[stat.DisplayName] = stat.Value
+		}
+
+		expected := map[string]float64{
+			"Server time":     2,
+			"Rows":            float64(frame.Rows()),
+			"Response size":   1234,
+			"Processing time": 0.5,
+		}
+		for name, value := range expected {
+			if stats[name] != value {
+				t.Errorf("%s: expected stat '%s' %v, got %v", frame.Name, name, value, stats[name])
+			}
+		}
+
+		custom := frame.Meta.Custom.(frameMetaCustom)
+		if custom.Version != frameMetaCustomVersion || custom.QueryRaw != "select * from $table" || *custom.ServerTimeMs != 2 {
+			t.Errorf("%s: unexpected custom metadata '%+v'", frame.Name, custom)
+		}
+	}
+}
+
+func TestQueryResponseSize(t *testing.T) {
+	body := `[{"status":"OK","time":"1ms","result":[{"value":1}]}]`
+	ds := testDatasource(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(body))
+	}), configuration{})
+
+	ctx := context.Background()
+	response, err := ds.query(ctx, ds.db, "select * from metric")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.size != len(body) {
+		t.Errorf("expected the size of the HTTP body %d, got %d", len(body), response.size)
+	}
+
+	// the websocket transport counts the query response, but not the signin
+	location := newFakeSurrealDB(t, 0, nil).location()
+	p, err := newPool(ctx, location, nil, configuration{}, poolOptions{minSize: 1, maxSize: 1, idleTimeout: time.Minute, healthCheckInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.close()
+
+	response, err = ds.query(ctx, p, "select * from metric")
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := `{"id":"2","result":[{"result":[],"status":"OK","time":"1ms"}],"error":null}`
+	if response.size != len(message) {
+		t.Errorf("expected the size of the query message %d, got %d", len(message), response.size)
+	}
+}
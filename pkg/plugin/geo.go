@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// GeoJSON types of SurrealDB geometry values
+// https://surrealdb.com/docs/surrealdb/surrealql/datamodel/geometries
+var geoTypes = map[string]bool{
+	"Point":              true,
+	"LineString":         true,
+	"Polygon":            true,
+	"MultiPoint":         true,
+	"MultiLineString":    true,
+	"MultiPolygon":       true,
+	"GeometryCollection": true,
+}
+
+// returns the cell as GeoJSON geometry
+func geoGeometry(cell interface{}) (map[string]interface{}, bool) {
+	geometry, isMap := cell.(map[string]interface{})
+	if isMap == false {
+		return nil, false
+	}
+	kind, isKind := geometry["type"].(string)
+	if isKind == false || geoTypes[kind] == false {
+		return nil, false
+	}
+	if kind == "GeometryCollection" {
+		_, hasGeometries := geometry["geometries"].([]interface{})
+		return geometry, hasGeometries
+	}
+	_, hasCoordinates := geometry["coordinates"].([]interface{})
+	return geometry, hasCoordinates
+}
+
+// returns the latitude and longitude of the cells, if all geometries are points
+func geoPoints(cells []interface{}) ([]*float64, []*float64, bool) {
+	latitudes := make([]*float64, len(cells))
+	longitudes := make([]*float64, len(cells))
+	count := 0
+
+	for index, cell := range cells {
+		if cell == nil {
+			continue
+		}
+		geometry, isGeometry := geoGeometry(cell)
+		if isGeometry == false || geometry["type"] != "Point" {
+			return nil, nil, false
+		}
+		coordinates := geometry["coordinates"].([]interface{})
+		if len(coordinates) < 2 {
+			return nil, nil, false
+		}
+		longitude, isLongitude := coordinates[0].(float64)
+		latitude, isLatitude := coordinates[1].(float64)
+		if isLongitude == false || isLatitude == false {
+			return nil, nil, false
+		}
+		latitudes[index] = &latitude
+		longitudes[index] = &longitude
+		count++
+	}
+
+	return latitudes, longitudes, count > 0
+}
+
+// returns the GeoJSON of the cells, if all values are geometries
+func geoShapes(cells []interface{}) ([]*json.RawMessage, bool) {
+	shapes := make([]*json.RawMessage, len(cells))
+	count := 0
+
+	for index, cell := range cells {
+		if cell == nil {
+			continue
+		}
+		geometry, isGeometry := geoGeometry(cell)
+		if isGeometry == false {
+			return nil, false
+		}
+		bytes, err := json.Marshal(geometry)
+		if err != nil {
+			return nil, false
+		}
+		shape := json.RawMessage(bytes)
+		shapes[index] = &shape
+		count++
+	}
+
+	return shapes, count > 0
+}
+
+// detects the geometry columns and returns their fields for the geomap
+// panel, points are split into 'latitude' and 'longitude', which are prefixed
+// by the column name if several point columns exist or a column is already
+// named like that, other geometries are kept as GeoJSON, the returned keys
+// are the replaced columns
+func geoFields(columns map[string][]string, cells map[string][]interface{}) (map[string]interface{}, []string) {
+	keys := []string{}
+	for key := range columns {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	type point struct {
+		key        string
+		latitudes  []*float64
+		longitudes []*float64
+	}
+
+	points := []point{}
+	fields := make(map[string]interface{})
+	replaced := []string{}
+
+	for _, key := range keys {
+		if latitudes, longitudes, isPoints := geoPoints(cells[key]); isPoints {
+			points = append(points, point{key, latitudes, longitudes})
+			replaced = append(replaced, key)
+			continue
+		}
+		if shapes, isShapes := geoShapes(cells[key]); isShapes {
+			fields[key] = shapes
+			replaced = append(replaced, key)
+		}
+	}
+
+	// the plain names are only used for a single point column and if no other
+	// column has them, otherwise the names are prefixed by the point column
+	prefixed := len(points) > 1
+	for _, name := range []string{"latitude", "longitude"} {
+		if _, exists := columns[name]; exists && contains(replaced, name) == false {
+			prefixed = true
+		}
+	}
+
+	for _, entry := range points {
+		latitude := "latitude"
+		longitude := "longitude"
+		if prefixed {
+			latitude = entry.key + "_" + latitude
+			longitude = entry.key + "_" + longitude
+		}
+		fields[latitude] = entry.latitudes
+		fields[longitude] = entry.longitudes
+	}
+
+	return fields, replaced
+}
@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func geoTestPoint(longitude float64, latitude float64) map[string]interface{} {
+	return map[string]interface{}{"type": "Point", "coordinates": []interface{}{longitude, latitude}}
+}
+
+func TestGeoGeometry(t *testing.T) {
+	tests := []struct {
+		cell     interface{}
+		expected bool
+	}{
+		{geoTestPoint(-0.118, 51.509), true},
+		{map[string]interface{}{"type": "Polygon", "coordinates": []interface{}{}}, true},
+		{map[string]interface{}{"type": "GeometryCollection", "geometries": []interface{}{geoTestPoint(1, 2)}}, true},
+		{map[string]interface{}{"type": "GeometryCollection", "coordinates": []interface{}{}}, false},
+		{map[string]interface{}{"type": "Feature", "coordinates": []interface{}{}}, false},
+		{map[string]interface{}{"type": "Point"}, false},
+		{"Point", false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		_, isGeometry := geoGeometry(test.cell)
+		if isGeometry != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.cell, test.expected, isGeometry)
+		}
+	}
+}
+
+func TestGeoPoints(t *testing.T) {
+	latitudes, longitudes, isPoints := geoPoints([]interface{}{geoTestPoint(-0.118, 51.509), nil})
+	if isPoints == false {
+		t.Fatalf("expected points")
+	}
+	if *latitudes[0] != 51.509 || *longitudes[0] != -0.118 || latitudes[1] != nil || longitudes[1] != nil {
+		t.Errorf("unexpected coordinates %v, %v", latitudes, longitudes)
+	}
+
+	invalid := [][]interface{}{
+		{nil},
+		{geoTestPoint(1, 2), map[string]interface{}{"type": "Polygon", "coordinates": []interface{}{}}},
+		{map[string]interface{}{"type": "Point", "coordinates": []interface{}{"1", "2"}}},
+		{map[string]interface{}{"type": "Point", "coordinates": []interface{}{float64(1)}}},
+		{geoTestPoint(1, 2), "london"},
+	}
+	for _, cells := range invalid {
+		if _, _, isPoints := geoPoints(cells); isPoints {
+			t.Errorf("%v: expected no points", cells)
+		}
+	}
+}
+
+func TestGeoFields(t *testing.T) {
+	polygon := map[string]interface{}{
+		"type":        "Polygon",
+		"coordinates": []interface{}{[]interface{}{[]interface{}{float64(0), float64(0)}, []interface{}{float64(1), float64(0)}, []interface{}{float64(0), float64(0)}}},
+	}
+
+	columns := map[string][]string{"location": nil, "area": nil, "name": nil}
+	cells := map[string][]interface{}{
+		"location": {geoTestPoint(-0.118, 51.509), geoTestPoint(2.352, 48.857)},
+		"area":     {polygon, nil},
+		"name":     {"London", "Paris"},
+	}
+
+	fields, replaced := geoFields(columns, cells)
+	if len(replaced) != 2 || replaced[0] != "area" || replaced[1] != "location" {
+		t.Errorf("unexpected replaced columns %v", replaced)
+	}
+
+	latitudes, isLatitudes := fields["latitude"].([]*float64)
+	longitudes, isLongitudes := fields["longitude"].([]*float64)
+	if isLatitudes == false || isLongitudes == false || *latitudes[1] != 48.857 || *longitudes[1] != 2.352 {
+		t.Errorf("unexpected coordinates %v, %v", fields["latitude"], fields["longitude"])
+	}
+
+	shapes, isShapes := fields["area"].([]*json.RawMessage)
+	if isShapes == false || shapes[1] != nil {
+		t.Fatalf("unexpected shapes %v", fields["area"])
+	}
+	if value := string(*shapes[0]); value != `{"coordinates":[[[0,0],[1,0],[0,0]]],"type":"Polygon"}` {
+		t.Errorf("unexpected GeoJSON '%s'", value)
+	}
+
+	if _, exists := fields["name"]; exists {
+		t.Errorf("expected no field for 'name'")
+	}
+
+	columns["origin"] = nil
+	cells["origin"] = []interface{}{geoTestPoint(0, 0), geoTestPoint(0, 0)}
+
+	fields, _ = geoFields(columns, cells)
+	for _, key := range []string{"location_latitude", "location_longitude", "origin_latitude", "origin_longitude"} {
+		if _, exists := fields[key]; exists == false {
+			t.Errorf("expected field '%s'", key)
+		}
+	}
+
+	// an existing latitude column is kept
+	columns = map[string][]string{"location": nil, "latitude": {"north", "north"}}
+	cells = map[string][]interface{}{
+		"location": {geoTestPoint(-0.118, 51.509), geoTestPoint(2.352, 48.857)},
+		"latitude": {"north", "north"},
+	}
+
+	fields, replaced = geoFields(columns, cells)
+	if len(replaced) != 1 || replaced[0] != "location" {
+		t.Errorf("unexpected replaced columns %v", replaced)
+	}
+	if _, exists := fields["latitude"]; exists {
+		t.Errorf("expected the latitude column not to be replaced")
+	}
+	for _, key := range []string{"location_latitude", "location_longitude"} {
+		if _, exists := fields[key]; exists == false {
+			t.Errorf("expected field '%s'", key)
+		}
+	}
+}
+
+func TestFrameGeo(t *testing.T) {
+	ds := Datasource{}
+	query := queryData{request: queryRequestData{Timestamp: "timestamp", Geo: true}}
+
+	table := []map[string]interface{}{
+		{"id": "city:london", "location": geoTestPoint(-0.118, 51.509)},
+		{"id": "city:paris", "location": geoTestPoint(2.352, 48.857)},
+	}
+	head := map[string]int{"id": 0, "location": 1}
+
+	frame, err := ds.frame(&query, "A", head, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, field := range frame.Fields {
+		names = append(names, field.Name)
+	}
+	if len(names) != 3 || names[0] != "id" || names[1] != "latitude" || names[2] != "longitude" {
+		t.Errorf("unexpected fields %v", names)
+	}
+
+	query.request.Geo = false
+	frame, err = ds.frame(&query, "A", head, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field, _ := frame.FieldByName("location"); field == nil {
+		t.Errorf("expected the geometry as JSON without the geo option")
+	}
+}
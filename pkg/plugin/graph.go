@@ -0,0 +1,270 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// field names of the node graph panel
+// https://grafana.com/docs/grafana/latest/panels-visualizations/visualizations/node-graph/#data-api
+const (
+	graphFieldId            = "id"
+	graphFieldTitle         = "title"
+	graphFieldSource        = "source"
+	graphFieldTarget        = "target"
+	graphFieldMainStat      = "mainstat"
+	graphFieldSecondaryStat = "secondarystat"
+	graphFieldColor         = "color"
+	graphFieldDetail        = "detail__"
+)
+
+type graphElement struct {
+	id     string
+	source string
+	target string
+	record map[string]interface{}
+}
+
+// splits the result into node and edge records, the result is either an
+// array of records, where records with 'in' and 'out' are edges like the
+// records of a 'RELATE' table, or an object with the arrays 'nodes' and 'edges'
+func graphRecords(result interface{}) ([]map[string]interface{}, []map[string]interface{}, error) {
+	nodes := []map[string]interface{}{}
+	edges := []map[string]interface{}{}
+
+	var entries []interface{}
+	switch value := result.(type) {
+	case []interface{}:
+		entries = value
+	case map[string]interface{}:
+		nodeEntries, _ := value["nodes"].([]interface{})
+		edgeEntries, _ := value["edges"].([]interface{})
+		if nodeEntries == nil && edgeEntries == nil {
+			return nil, nil, fmt.Errorf("graph result '%v' contains no 'nodes' or 'edges' array", result)
+		}
+		entries = append(append(entries, nodeEntries...), edgeEntries...)
+	default:
+		return nil, nil, fmt.Errorf("graph returned '%v' instead of an array", result)
+	}
+
+	for _, entry := range entries {
+		record, isMap := entry.(map[string]interface{})
+		if isMap == false {
+			return nil, nil, fmt.Errorf("graph returned the invalid record '%v'", entry)
+		}
+		_, hasIn := record["in"]
+		_, hasOut := record["out"]
+		if hasIn && hasOut {
+			edges = append(edges, record)
+		} else {
+			nodes = append(nodes, record)
+		}
+	}
+
+	return nodes, edges, nil
+}
+
+// returns the record id of a value, fetched records are reduced to their 'id'
+func graphId(value interface{}) (string, bool) {
+	switch id := value.(type) {
+	case string:
+		return id, id != ""
+	case map[string]interface{}:
+		return graphId(id["id"])
+	}
+	return "", false
+}
+
+// converts edge records and optional node records into the 'nodes' and
+// 'edges' frames of the node graph panel, nodes which are only referenced by
+// an edge are added with their record id as title, the configured keys are
+// mapped to the main stat, secondary stat and color, other keys are details
+func (r *Datasource) graph(query *queryData, frameMeta *data.FrameMeta, dataResponse *backend.DataResponse) error {
+	nodeRecords, edgeRecords, err := graphRecords(query.response.result)
+	if err != nil {
+		return err
+	}
+
+	nodes := []graphElement{}
+	nodeIndex := map[string]int{}
+	for _, record := range nodeRecords {
+		id, isId := graphId(record["id"])
+		if isId == false {
+			return fmt.Errorf("graph node '%v' has no 'id'", record)
+		}
+		if index, exists := nodeIndex[id]; exists {
+			nodes[index].record = record
+			continue
+		}
+		nodeIndex[id] = len(nodes)
+		nodes = append(nodes, graphElement{id: id, record: record})
+	}
+
+	edges := []graphElement{}
+	for _, record := range edgeRecords {
+		source, isSource := graphId(record["in"])
+		target, isTarget := graphId(record["out"])
+		if isSource == false || isTarget == false {
+			return fmt.Errorf("graph edge '%v' has no valid 'in' and 'out'", record)
+		}
+		id, isId := graphId(record["id"])
+		if isId == false {
+			id = fmt.Sprintf("%s->%s", source, target)
+		}
+		edges = append(edges, graphElement{id: id, source: source, target: target, record: record})
+
+		for _, node := range []string{source, target} {
+			if _, exists := nodeIndex[node]; exists == false {
+				nodeIndex[node] = len(nodes)
+				nodes = append(nodes, graphElement{id: node})
+			}
+		}
+	}
+
+	request := query.request
+
+	nodeId := []string{}
+	nodeTitle := []string{}
+	for _, node := range nodes {
+		nodeId = append(nodeId, node.id)
+		title, isTitle := node.record[request.GraphTitle]
+		if isTitle && title != nil {
+			nodeTitle = append(nodeTitle, fmt.Sprintf("%v", title))
+		} else {
+			nodeTitle = append(nodeTitle, node.id)
+		}
+	}
+
+	nodeFrame := data.NewFrame("nodes",
+		data.NewField(graphFieldId, nil, nodeId),
+		data.NewField(graphFieldTitle, nil, nodeTitle),
+	)
+	nodeFrame.Fields = append(nodeFrame.Fields, graphFields(request, nodes, "id", request.GraphTitle)...)
+
+	edgeId := []string{}
+	edgeSource := []string{}
+	edgeTarget := []string{}
+	for _, edge := range edges {
+		edgeId = append(edgeId, edge.id)
+		edgeSource = append(edgeSource, edge.source)
+		edgeTarget = append(edgeTarget, edge.target)
+	}
+
+	edgeFrame := data.NewFrame("edges",
+		data.NewField(graphFieldId, nil, edgeId),
+		data.NewField(graphFieldSource, nil, edgeSource),
+		data.NewField(graphFieldTarget, nil, edgeTarget),
+	)
+	edgeFrame.Fields = append(edgeFrame.Fields, graphFields(request, edges, "id", "in", "out")...)
+
+	for _, frame := range []*data.Frame{nodeFrame, edgeFrame} {
+		meta := *frameMeta
+		meta.PreferredVisualization = data.VisTypeNodeGraph
+		frame.Meta = &meta
+		dataResponse.Frames = append(dataResponse.Frames, frame)
+	}
+
+	return nil
+}
+
+// returns the stat and color fields of the configured keys and a detail field
+// of every other key, fields without any value are omitted
+func graphFields(request queryRequestData, elements []graphElement, skip ...string) []*data.Field {
+	fields := []*data.Field{}
+
+	mapped := map[string]bool{}
+	for _, key := range skip {
+		mapped[key] = true
+	}
+
+	for _, stat := range []struct {
+		name string
+		key  string
+	}{
+		{graphFieldMainStat, request.GraphMainStat},
+		{graphFieldSecondaryStat, request.GraphSecondaryStat},
+		{graphFieldColor, request.GraphColor},
+	} {
+		mapped[stat.key] = true
+		field := graphField(stat.name, stat.key, elements, false)
+		if field == nil {
+			continue
+		}
+		field.Config = &data.FieldConfig{DisplayName: stat.key}
+		if stat.name == graphFieldColor && field.Type() == data.FieldTypeNullableFloat64 {
+			// numeric colors are mapped by the color scheme of the field
+			field.Config.Color = map[string]interface{}{"mode": "continuous-GrYlRd"}
+		}
+		fields = append(fields, field)
+	}
+
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, element := range elements {
+		for key := range element.record {
+			if mapped[key] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := graphField(graphFieldDetail+key, key, elements, true)
+		field.Config = &data.FieldConfig{DisplayName: key}
+		fields = append(fields, field)
+	}
+
+	return fields
+}
+
+// returns a numeric field if all values are numbers, otherwise a string field
+// where objects and arrays are encoded as JSON, or nil if no value is present
+func graphField(name string, key string, elements []graphElement, detail bool) *data.Field {
+	numbers := []*float64{}
+	texts := []*string{}
+	numeric := true
+	present := false
+
+	for _, element := range elements {
+		value, exists := element.record[key]
+		if exists == false || value == nil {
+			numbers = append(numbers, nil)
+			texts = append(texts, nil)
+			continue
+		}
+		present = true
+
+		if number, isNumber := value.(float64); isNumber {
+			numbers = append(numbers, &number)
+		} else if decimal, isDecimal := value.(json.Number); isDecimal {
+			number, _ := decimal.Float64()
+			numbers = append(numbers, &number)
+		} else {
+			numeric = false
+			numbers = append(numbers, nil)
+		}
+
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			texts = append(texts, explainJSON(value))
+		default:
+			text := fmt.Sprintf("%v", value)
+			texts = append(texts, &text)
+		}
+	}
+
+	if present == false && detail == false {
+		return nil
+	}
+	if present && numeric {
+		return data.NewField(name, nil, numbers)
+	}
+	return data.NewField(name, nil, texts)
+}
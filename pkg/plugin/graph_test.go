@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestGraphRecords(t *testing.T) {
+	tests := []struct {
+		result  interface{}
+		nodes   int
+		edges   int
+		invalid bool
+	}{
+		{[]interface{}{}, 0, 0, false},
+		{[]interface{}{
+			map[string]interface{}{"id": "service:api"},
+			map[string]interface{}{"id": "calls:1", "in": "service:api", "out": "service:db"},
+		}, 1, 1, false},
+		{map[string]interface{}{
+			"nodes": []interface{}{map[string]interface{}{"id": "service:api"}},
+			"edges": []interface{}{map[string]interface{}{"id": "calls:1", "in": "service:api", "out": "service:db"}},
+		}, 1, 1, false},
+		{map[string]interface{}{"id": "service:api"}, 0, 0, true},
+		{[]interface{}{"service:api"}, 0, 0, true},
+		{"service:api", 0, 0, true},
+	}
+
+	for index, test := range tests {
+		nodes, edges, err := graphRecords(test.result)
+		if (err != nil) != test.invalid {
+			t.Errorf("%d: unexpected error '%v'", index, err)
+			continue
+		}
+		if len(nodes) != test.nodes || len(edges) != test.edges {
+			t.Errorf("%d: expected %d nodes and %d edges, got %d and %d", index, test.nodes, test.edges, len(nodes), len(edges))
+		}
+	}
+}
+
+func TestGraphId(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+		valid    bool
+	}{
+		{"service:api", "service:api", true},
+		{map[string]interface{}{"id": "service:api", "name": "api"}, "service:api", true},
+		{"", "", false},
+		{nil, "", false},
+		{float64(1), "", false},
+	}
+
+	for _, test := range tests {
+		id, valid := graphId(test.value)
+		if id != test.expected || valid != test.valid {
+			t.Errorf("%v: expected '%s' (%v), got '%s' (%v)", test.value, test.expected, test.valid, id, valid)
+		}
+	}
+}
+
+func TestGraph(t *testing.T) {
+	query := queryData{
+		name: "A",
+		request: queryRequestData{
+			GraphTitle:         "name",
+			GraphMainStat:      "mainStat",
+			GraphSecondaryStat: "latency",
+			GraphColor:         "color",
+		},
+		response: queryResponseData{
+			result: []interface{}{
+				map[string]interface{}{"id": "service:api", "name": "API", "mainStat": float64(120), "color": "green"},
+				map[string]interface{}{"id": "calls:1", "in": "service:api", "out": "service:db", "latency": float64(4.5), "protocol": "tcp"},
+				map[string]interface{}{"id": "calls:2", "in": map[string]interface{}{"id": "service:web"}, "out": "service:api", "latency": float64(12)},
+			},
+		},
+	}
+
+	ds := Datasource{}
+	frameMeta := &data.FrameMeta{ExecutedQueryString: "select * from service, calls"}
+
+	var dataResponse backend.DataResponse
+	err := ds.graph(&query, frameMeta, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dataResponse.Frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(dataResponse.Frames))
+	}
+
+	nodes := dataResponse.Frames[0]
+	if nodes.Name != "nodes" || nodes.Meta.PreferredVisualization != data.VisTypeNodeGraph {
+		t.Errorf("unexpected nodes frame '%s' with visualization '%s'", nodes.Name, nodes.Meta.PreferredVisualization)
+	}
+	if nodes.Rows() != 3 {
+		t.Fatalf("expected 3 nodes, got %d", nodes.Rows())
+	}
+
+	titles := []string{"API", "service:db", "service:web"}
+	for index, title := range titles {
+		if value := nodes.Fields[1].At(index); value != title {
+			t.Errorf("node %d: expected title '%s', got '%v'", index, title, value)
+		}
+	}
+
+	mainStat, _ := nodes.FieldByName(graphFieldMainStat)
+	if mainStat == nil || *mainStat.At(0).(*float64) != 120 || mainStat.At(1).(*float64) != nil {
+		t.Errorf("unexpected node main stat '%v'", mainStat)
+	}
+	color, _ := nodes.FieldByName(graphFieldColor)
+	if color == nil || *color.At(0).(*string) != "green" {
+		t.Errorf("unexpected node color '%v'", color)
+	}
+
+	edges := dataResponse.Frames[1]
+	if edges.Name != "edges" || edges.Rows() != 2 {
+		t.Fatalf("unexpected edges frame '%s' with %d rows", edges.Name, edges.Rows())
+	}
+	if source := edges.Fields[1].At(1); source != "service:web" {
+		t.Errorf("expected source 'service:web', got '%v'", source)
+	}
+
+	secondaryStat, _ := edges.FieldByName(graphFieldSecondaryStat)
+	if secondaryStat == nil || *secondaryStat.At(0).(*float64) != 4.5 {
+		t.Errorf("unexpected edge secondary stat '%v'", secondaryStat)
+	}
+	if _, found := edges.FieldByName(graphFieldMainStat); found != -1 {
+		t.Errorf("expected no edge main stat")
+	}
+
+	protocol, _ := edges.FieldByName(graphFieldDetail + "protocol")
+	if protocol == nil || *protocol.At(0).(*string) != "tcp" || protocol.At(1).(*string) != nil {
+		t.Errorf("unexpected edge detail '%v'", protocol)
+	}
+
+	if frameMeta.PreferredVisualization != "" {
+		t.Errorf("expected the shared frame meta to be unchanged")
+	}
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// diagnostics of the health check, reported as 'CheckHealthResult.JSONDetails'
+type healthDetails struct {
+	Version   string   `json:"version"`
+	Location  string   `json:"location"`
+	Transport string   `json:"transport"`
+	Namespace string   `json:"namespace"`
+	Database  string   `json:"database"`
+	AuthLevel string   `json:"authLevel"`
+	Auth      string   `json:"auth"`
+	LatencyMs float64  `json:"latencyMs"`
+	Tables    []string `json:"tables"`
+	Warnings  []string `json:"warnings,omitempty"`
+	Error     string   `json:"error,omitempty"`
+
+	// the state of each endpoint, if several endpoints are configured
+	Endpoints []endpointState `json:"endpoints,omitempty"`
+}
+
+// resolves the session, measures the round-trip latency and verifies that
+// the namespace and database exist and their tables can be listed
+func (r *Datasource) diagnose(ctx context.Context, db transport) (healthDetails, error) {
+	details := healthDetails{
+		Version:   describeVersion(r.config),
+		Location:  fmt.Sprintf("%s://%s", r.config.Scheme, r.config.Location),
+		Transport: describeTLS(r.config),
+		Namespace: r.config.Namespace,
+		Database:  r.config.Database,
+		AuthLevel: authMethod(r.config),
+		Auth:      describeAuth(r.config),
+		Tables:    []string{},
+	}
+
+	if db, isCluster := db.(*cluster); isCluster {
+		for _, state := range db.states(ctx) {
+			details.Endpoints = append(details.Endpoints, state)
+			if state.Status != "up" {
+				details.Warnings = append(details.Warnings, fmt.Sprintf("endpoint '%s' is down", state.Location))
+			}
+		}
+	}
+
+	if version, release, err := detectVersion(ctx, r.config); err == nil {
+		details.Version = "SurrealDB " + release
+		if version != r.config.Version {
+			details.Warnings = append(details.Warnings, fmt.Sprintf(
+				"server version %s differs from the configured major version %d",
+				release,
+				r.config.Version,
+			))
+		}
+	}
+
+	query := `
+return
+{ database : session::db()
+, namespace : session::ns()
+, origin : session::origin()
+}
+`
+
+	start := time.Now()
+	response, err := r.query(ctx, db, query)
+	details.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		return details, err
+	}
+
+	session, err := statementResult(response)
+	if err != nil {
+		return details, err
+	}
+	if sessionMap, isMap := session.(map[string]interface{}); isMap {
+		if namespace, isString := sessionMap["namespace"].(string); isString {
+			details.Namespace = namespace
+		}
+		if database, isString := sessionMap["database"].(string); isString {
+			details.Database = database
+		}
+	}
+
+	// the namespace and database are only listed for root and namespace users,
+	// other users can not see them and the check is skipped on errors
+	switch details.AuthLevel {
+	case _ROOT:
+		infoQuery := "info for kv"
+		if r.config.Version >= 2 {
+			infoQuery = "info for root"
+		}
+		namespaces, err := r.info(ctx, db, infoQuery, "namespaces", "ns")
+		if err == nil && contains(namespaces, details.Namespace) == false {
+			details.Warnings = append(details.Warnings, fmt.Sprintf("namespace '%s' does not exist", details.Namespace))
+			return details, nil
+		}
+		fallthrough
+	case _NAMESPACE:
+		databases, err := r.info(ctx, db, "info for ns", "databases", "db")
+		if err == nil && contains(databases, details.Database) == false {
+			details.Warnings = append(details.Warnings, fmt.Sprintf("database '%s' does not exist", details.Database))
+			return details, nil
+		}
+	}
+
+	tables, err := r.info(ctx, db, "info for db", "tables", "tb")
+	if err != nil {
+		if strings.Contains(err.Error(), "does not exist") {
+			details.Warnings = append(details.Warnings, err.Error())
+		} else {
+			details.Warnings = append(details.Warnings, fmt.Sprintf("listing tables failed: %v", err))
+		}
+		return details, nil
+	}
+	details.Tables = tables
+
+	return details, nil
+}
+
+// returns the sorted names of an 'INFO FOR' statement, SurrealDB 1.x uses
+// short keys like 'tb' up to 1.0 and long keys like 'tables' since then
+func (r *Datasource) info(ctx context.Context, db transport, query string, keys ...string) ([]string, error) {
+	response, err := r.query(ctx, db, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := statementResult(response)
+	if err != nil {
+		return nil, err
+	}
+
+	resultMap, isMap := result.(map[string]interface{})
+	if isMap == false {
+		return nil, fmt.Errorf("invalid '%s' result '%v'", query, result)
+	}
+
+	names := []string{}
+	for _, key := range keys {
+		entries, isEntries := resultMap[key].(map[string]interface{})
+		if isEntries == false {
+			continue
+		}
+		for name := range entries {
+			names = append(names, name)
+		}
+		break
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// returns the result of a statement, or its error if the status is not 'OK'
+func statementResult(response queryResponseData) (interface{}, error) {
+	if response.status != "OK" {
+		return nil, fmt.Errorf("%v", response.result)
+	}
+	return response.result, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, entry := range values {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestCheckHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/version" {
+			w.Write([]byte("surrealdb-1.5.3"))
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		query := strings.TrimSpace(string(body))
+
+		statement := map[string]interface{}{"status": "OK", "time": "1ms"}
+		switch {
+		case strings.Contains(query, "session::db()"):
+			statement["result"] = map[string]interface{}{"namespace": req.Header.Get("NS"), "database": req.Header.Get("DB")}
+		case query == "info for kv":
+			statement["result"] = map[string]interface{}{"namespaces": map[string]interface{}{"test": "DEFINE NAMESPACE test"}}
+		case query == "info for ns":
+			statement["result"] = map[string]interface{}{"databases": map[string]interface{}{"test": "DEFINE DATABASE test"}}
+		case query == "info for db" && req.Header.Get("DB") == "forbidden":
+			statement["status"] = "ERR"
+			statement["result"] = "IAM error: Not enough permissions"
+		case query == "info for db":
+			statement["result"] = map[string]interface{}{"tables": map[string]interface{}{"metric": "", "log": ""}}
+		default:
+			statement["result"] = true
+		}
+		json.NewEncoder(w).Encode([]interface{}{statement})
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	options := poolOptions{maxSize: 1, idleTimeout: time.Minute}
+
+	tests := []struct {
+		config   configuration
+		status   backend.HealthStatus
+		tables   []string
+		warnings []string
+	}{
+		{configuration{Namespace: "test", Database: "test"}, backend.HealthStatusOk, []string{"log", "metric"}, nil},
+		{configuration{Namespace: "test", Database: "test", AuthMethod: _ROOT}, backend.HealthStatusOk, []string{"log", "metric"}, nil},
+		{configuration{Namespace: "missing", Database: "test", AuthMethod: _ROOT}, backend.HealthStatusOk, []string{}, []string{"namespace 'missing' does not exist"}},
+		{configuration{Namespace: "test", Database: "missing", AuthMethod: _NAMESPACE}, backend.HealthStatusOk, []string{}, []string{"database 'missing' does not exist"}},
+		{configuration{Namespace: "test", Database: "forbidden"}, backend.HealthStatusOk, []string{}, []string{"listing tables failed: IAM error: Not enough permissions"}},
+	}
+
+	for _, test := range tests {
+		test.config.Scheme = _WS
+		test.config.Location = strings.TrimPrefix(server.URL, "http://")
+		test.config.Version = 1
+
+		db, err := newHTTPTransport(ctx, server.URL, nil, test.config, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ds := Datasource{db: db, config: test.config}
+		result, err := ds.CheckHealth(ctx, &backend.CheckHealthRequest{})
+		db.close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != test.status {
+			t.Errorf("%+v: unexpected status '%v': %s", test.config, result.Status, result.Message)
+		}
+
+		var details healthDetails
+		err = json.Unmarshal(result.JSONDetails, &details)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if details.Version != "SurrealDB 1.5.3" || details.Namespace != test.config.Namespace || details.Database != test.config.Database {
+			t.Errorf("%+v: unexpected details '%+v'", test.config, details)
+		}
+		if reflect.DeepEqual(details.Tables, test.tables) == false {
+			t.Errorf("%+v: expected tables '%v', got '%v'", test.config, test.tables, details.Tables)
+		}
+		if reflect.DeepEqual(details.Warnings, test.warnings) == false {
+			t.Errorf("%+v: expected warnings '%v', got '%v'", test.config, test.warnings, details.Warnings)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// returns a pointer to the value, for the nullable fields of test frames
+func floatPointer(v float64) *float64 {
+	return &v
+}
+
+// returns a pointer to the UTC time of the unix seconds
+func unixTime(seconds int) *time.Time {
+	v := time.Unix(int64(seconds), 0).UTC()
+	return &v
+}
+
+// answers every request of the HTTP transport with the statement result
+func sqlResult(serverTime string, result interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(w).Encode([]interface{}{
+			map[string]interface{}{"status": "OK", "time": serverTime, "result": result},
+		})
+	}
+}
+
+// returns a datasource querying the handler with the HTTP transport, the
+// datasource and the server are closed at the end of the test
+func testDatasource(t *testing.T, handler http.Handler, config configuration) *Datasource {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	config.Transport = _HTTP
+	if config.Scheme == "" {
+		config.Scheme = _HTTP
+	}
+	config.Location = server.Listener.Addr().String()
+	if config.Pool.maxSize == 0 {
+		config.Pool = poolOptions{maxSize: 1, idleTimeout: time.Minute}
+	}
+
+	db, err := newTransport(context.Background(), config, config.Pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := &Datasource{db: db, config: config}
+	t.Cleanup(ds.Dispose)
+	return ds
+}
+
+// fakeSurrealDB is a websocket RPC server, which records the requests and
+// answers signins with a token and queries with an empty result
+type fakeSurrealDB struct {
+	*httptest.Server
+
+	signins atomic.Int32
+
+	mutex    sync.Mutex
+	requests []rpcRequest
+}
+
+// starts the server, which drops the websocket after the given number of
+// requests per connection unless it is 0, the optional 'respond' replaces the
+// default response message, the server is closed at the end of the test
+func newFakeSurrealDB(t *testing.T, dropAfter int, respond func(request rpcRequest) []byte) *fakeSurrealDB {
+	server := &fakeSurrealDB{}
+	upgrader := websocket.Upgrader{}
+
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		for requests := 0; dropAfter == 0 || requests < dropAfter; requests++ {
+			var request rpcRequest
+			err := conn.ReadJSON(&request)
+			if err != nil {
+				return
+			}
+
+			server.mutex.Lock()
+			server.requests = append(server.requests, request)
+			server.mutex.Unlock()
+
+			if respond != nil {
+				conn.WriteMessage(websocket.TextMessage, respond(request))
+				continue
+			}
+
+			var result interface{}
+			switch request.Method {
+			case "signin":
+				server.signins.Add(1)
+				result = "token"
+			case "query":
+				result = []interface{}{
+					map[string]interface{}{"status": "OK", "time": "1ms", "result": []interface{}{}},
+				}
+			}
+
+			response, _ := json.Marshal(rpcResponse{ID: request.ID, Result: result})
+			conn.WriteMessage(websocket.TextMessage, response)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// returns the websocket location of the RPC endpoint
+func (s *fakeSurrealDB) location() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/rpc"
+}
+
+// returns the recorded requests without their IDs and forgets them
+func (s *fakeSurrealDB) recorded() []rpcRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	requests := append([]rpcRequest{}, s.requests...)
+	s.requests = nil
+	for index := range requests {
+		requests[index].ID = ""
+	}
+	return requests
+}
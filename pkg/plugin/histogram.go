@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// https://grafana.com/developers/dataplane/heatmap
+const (
+	frameTypeHeatmapRows  data.FrameType = "heatmap-rows"
+	frameTypeHeatmapCells data.FrameType = "heatmap-cells"
+)
+
+const (
+	defaultHistogramCount  = 10
+	defaultHistogramWidth  = 1
+	defaultHistogramFactor = 2
+)
+
+// computes the lower bound of the first bucket and the sorted upper bounds of
+// the histogram buckets, values above the last bound are counted in an
+// additional '+Inf' bucket and values below the lower bound are clamped into
+// the first bucket, which keeps the heatmap axis finite
+func histogramBounds(request queryRequestData) (float64, []float64, error) {
+	count := request.HistogramCount
+	if count == 0 {
+		count = defaultHistogramCount
+	}
+	if count < 0 {
+		return 0, nil, fmt.Errorf("invalid histogram bucket count '%d'", count)
+	}
+
+	lower := float64(0)
+	bounds := []float64{}
+
+	switch request.HistogramBuckets {
+	case "", "linear":
+		width := request.HistogramWidth
+		if width == 0 {
+			width = defaultHistogramWidth
+		}
+		if width < 0 {
+			return 0, nil, fmt.Errorf("invalid histogram bucket width '%v'", width)
+		}
+
+		lower = request.HistogramStart
+		for index := 1; index <= count; index++ {
+			bounds = append(bounds, request.HistogramStart+width*float64(index))
+		}
+	case "exponential":
+		start := request.HistogramStart
+		if start == 0 {
+			start = 1
+		}
+		factor := request.HistogramFactor
+		if factor == 0 {
+			factor = defaultHistogramFactor
+		}
+		if start < 0 || factor <= 1 {
+			return 0, nil, fmt.Errorf("invalid exponential histogram start '%v' or factor '%v'", start, factor)
+		}
+
+		// the start is the first upper bound, the first bucket spans one factor
+		lower = start / factor
+		for index := 0; index < count; index++ {
+			bounds = append(bounds, start*math.Pow(factor, float64(index)))
+		}
+	case "explicit":
+		bounds = append(bounds, request.HistogramBounds...)
+		if len(bounds) == 0 {
+			return 0, nil, fmt.Errorf("explicit histogram buckets require bounds")
+		}
+
+		for index := 1; index < len(bounds); index++ {
+			if bounds[index] <= bounds[index-1] {
+				return 0, nil, fmt.Errorf("explicit histogram bounds must be strictly increasing")
+			}
+		}
+
+		// the first bucket is as wide as the second one, or 1 for a single bound
+		width := float64(1)
+		if len(bounds) > 1 {
+			width = bounds[1] - bounds[0]
+		}
+		lower = bounds[0] - width
+	default:
+		return 0, nil, fmt.Errorf("unsupported histogram buckets '%s'", request.HistogramBuckets)
+	}
+
+	return lower, bounds, nil
+}
+
+func histogramBucketName(bound float64) string {
+	if math.IsInf(bound, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
+
+// https://grafana.com/docs/grafana/latest/panels-visualizations/visualizations/heatmap/
+func (r *Datasource) histogram(query *queryData, dataResponse *backend.DataResponse) error {
+	frames := dataResponse.Frames
+	if len(frames) != 1 {
+		return fmt.Errorf("multiple frames are not supported yet")
+	}
+
+	frame := frames[0]
+
+	timeField := frame.Fields[0] // see 'metric()'
+	dataField := frame.Fields[1] // see 'metric()'
+
+	lower, bounds, err := histogramBounds(query.request)
+	if err != nil {
+		return err
+	}
+	bounds = append(bounds, math.Inf(1))
+
+	timeData, bucketData, err := r.metricBuckets(query, timeField, dataField)
+	if err != nil {
+		return err
+	}
+
+	counts := make([][]int64, len(bounds))
+	for boundIndex := range bounds {
+		counts[boundIndex] = make([]int64, len(timeData))
+	}
+
+	for timeIndex, values := range bucketData {
+		for _, value := range values {
+			if value == nil || math.IsNaN(*value) {
+				continue
+			}
+
+			boundIndex := sort.SearchFloat64s(bounds, *value)
+			counts[boundIndex][timeIndex]++
+		}
+	}
+
+	histogramFrame := data.NewFrame(frame.Name)
+
+	switch query.request.HistogramFormat {
+	case "", "rows":
+		histogramFrame.Fields = append(histogramFrame.Fields, data.NewField(timeField.Name, nil, timeData))
+
+		for boundIndex, bound := range bounds {
+			name := histogramBucketName(bound)
+			field := data.NewField(name, data.Labels{"le": name}, counts[boundIndex])
+			histogramFrame.Fields = append(histogramFrame.Fields, field)
+		}
+
+		histogramFrame.Meta = &data.FrameMeta{Type: frameTypeHeatmapRows}
+	case "cells":
+		xMin := data.NewField("xMin", nil, []time.Time{})
+		yMin := data.NewField("yMin", nil, []float64{})
+		yMax := data.NewField("yMax", nil, []float64{})
+		count := data.NewField("count", nil, []int64{})
+
+		for timeIndex, timeValue := range timeData {
+			bucketLower := lower
+			for boundIndex, bound := range bounds {
+				if counts[boundIndex][timeIndex] != 0 {
+					xMin.Append(timeValue)
+					yMin.Append(bucketLower)
+					yMax.Append(bound)
+					count.Append(counts[boundIndex][timeIndex])
+				}
+				bucketLower = bound
+			}
+		}
+
+		histogramFrame.Fields = append(histogramFrame.Fields, xMin, yMin, yMax, count)
+		histogramFrame.Meta = &data.FrameMeta{Type: frameTypeHeatmapCells}
+	default:
+		return fmt.Errorf("unsupported histogram format '%s'", query.request.HistogramFormat)
+	}
+
+	if frame.Meta != nil {
+		histogramFrame.Meta.PreferredVisualization = frame.Meta.PreferredVisualization
+		histogramFrame.Meta.Custom = frame.Meta.Custom
+	}
+
+	dataResponse.Frames = []*data.Frame{histogramFrame}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestHistogramBounds(t *testing.T) {
+	tests := []struct {
+		request  queryRequestData
+		lower    float64
+		expected []float64
+	}{
+		{queryRequestData{HistogramCount: 3}, 0, []float64{1, 2, 3}},
+		{queryRequestData{HistogramCount: 3, HistogramStart: 10, HistogramWidth: 5}, 10, []float64{15, 20, 25}},
+		{queryRequestData{HistogramBuckets: "exponential", HistogramCount: 4}, 0.5, []float64{1, 2, 4, 8}},
+		{queryRequestData{HistogramBuckets: "exponential", HistogramCount: 2, HistogramStart: 10, HistogramFactor: 10}, 1, []float64{10, 100}},
+		{queryRequestData{HistogramBuckets: "explicit", HistogramBounds: []float64{0.25, 0.5, 1}}, 0, []float64{0.25, 0.5, 1}},
+		{queryRequestData{HistogramBuckets: "explicit", HistogramBounds: []float64{5}}, 4, []float64{5}},
+	}
+
+	for _, test := range tests {
+		lower, bounds, err := histogramBounds(test.request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if lower != test.lower {
+			t.Errorf("%+v: expected the lower bound %v, got %v", test.request, test.lower, lower)
+		}
+
+		if len(bounds) != len(test.expected) {
+			t.Fatalf("%+v: expected %v, got %v", test.request, test.expected, bounds)
+		}
+		for index := range bounds {
+			if bounds[index] != test.expected[index] {
+				t.Errorf("%+v: expected %v, got %v", test.request, test.expected, bounds)
+			}
+		}
+	}
+
+	_, _, err := histogramBounds(queryRequestData{HistogramBuckets: "explicit", HistogramBounds: []float64{1, 1}})
+	if err == nil {
+		t.Error("explicit bounds must be strictly increasing")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	from := *unixTime(0)
+
+	dataResponse := backend.DataResponse{
+		Frames: []*data.Frame{
+			data.NewFrame("A",
+				data.NewField("timestamp", nil, []*time.Time{unixTime(1), unixTime(2), unixTime(11)}),
+				data.NewField("value", nil, []*float64{floatPointer(0.5), floatPointer(1.5), floatPointer(7)}),
+			),
+		},
+	}
+
+	query := queryData{
+		request: queryRequestData{
+			HistogramBuckets: "explicit",
+			HistogramBounds:  []float64{1, 2},
+		},
+		timeFrom: from,
+		timeTo:   from.Add(19 * time.Second),
+		interval: 10 * time.Second,
+	}
+
+	err := (&Datasource{}).histogram(&query, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := dataResponse.Frames[0]
+	if frame.Meta.Type != frameTypeHeatmapRows {
+		t.Errorf("unexpected frame type '%s'", frame.Meta.Type)
+	}
+
+	expected := map[string][]int64{
+		"1":    {1, 0},
+		"2":    {1, 0},
+		"+Inf": {0, 1},
+	}
+	for _, field := range frame.Fields[1:] {
+		for index, count := range expected[field.Name] {
+			if field.At(index).(int64) != count {
+				t.Errorf("bucket '%s': unexpected count at %d", field.Name, index)
+			}
+		}
+	}
+}
+
+func TestHistogramCells(t *testing.T) {
+	from := *unixTime(0)
+
+	dataResponse := backend.DataResponse{
+		Frames: []*data.Frame{
+			data.NewFrame("A",
+				data.NewField("timestamp", nil, []*time.Time{unixTime(1), unixTime(2), unixTime(3)}),
+				data.NewField("value", nil, []*float64{floatPointer(-3), floatPointer(12), floatPointer(40)}),
+			),
+		},
+	}
+
+	query := queryData{
+		request: queryRequestData{
+			HistogramStart:  10,
+			HistogramWidth:  5,
+			HistogramCount:  2,
+			HistogramFormat: "cells",
+		},
+		timeFrom: from,
+		timeTo:   from.Add(9 * time.Second),
+		interval: 10 * time.Second,
+	}
+
+	err := (&Datasource{}).histogram(&query, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := dataResponse.Frames[0]
+	if frame.Meta.Type != frameTypeHeatmapCells {
+		t.Errorf("unexpected frame type '%s'", frame.Meta.Type)
+	}
+
+	// the value below the start is clamped into the first bucket
+	expected := [][]float64{{10, 15, 2}, {20, math.Inf(1), 1}}
+	if frame.Rows() != len(expected) {
+		t.Fatalf("expected %d cells, got %d", len(expected), frame.Rows())
+	}
+	for index, cell := range expected {
+		yMin := frame.Fields[1].At(index).(float64)
+		yMax := frame.Fields[2].At(index).(float64)
+		count := frame.Fields[3].At(index).(int64)
+		if yMin != cell[0] || yMax != cell[1] || float64(count) != cell[2] {
+			t.Errorf("cell %d: expected %v, got [%v %v %v]", index, cell, yMin, yMax, count)
+		}
+	}
+}
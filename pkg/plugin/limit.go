@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// page size of paginated queries without a row limit
+const defaultPageSize = 1000
+
+// position of the next page, encoded as opaque 'FrameMeta.Custom.cursor'
+type pageCursor struct {
+	Start int `json:"start"`
+}
+
+func encodeCursor(start int) string {
+	bytes, _ := json.Marshal(pageCursor{Start: start})
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	bytes, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor '%s'", cursor)
+	}
+
+	var page pageCursor
+	err = json.Unmarshal(bytes, &page)
+	if err != nil || page.Start < 0 {
+		return 0, fmt.Errorf("invalid cursor '%s'", cursor)
+	}
+
+	return page.Start, nil
+}
+
+// returns the row limit of the query, which can not exceed the row limit of
+// the datasource, paginated queries are limited to a page, zero is unlimited
+func rowLimit(maxRows int, request queryRequestData) int {
+	limit := request.Limit
+	if maxRows > 0 && (limit <= 0 || limit > maxRows) {
+		limit = maxRows
+	}
+	if request.Paginate && limit <= 0 {
+		limit = defaultPageSize
+	}
+	return limit
+}
+
+// wraps the last statement, if it is a SELECT statement, like
+// 'SELECT * FROM (...) LIMIT ... START ...', one row more than the limit is
+// requested to detect a truncation, other statements are limited by
+// 'limitResult()', the statements are split by 'splitStatements()'
+func limitQuery(surql string, limit int, start int) (string, bool) {
+	statements := splitStatements(surql)
+	if len(statements) == 0 {
+		return strings.TrimSpace(surql), false
+	}
+
+	last := statements[len(statements)-1]
+	if last.keyword() != "SELECT" {
+		return joinStatements(statements), false
+	}
+
+	head := ""
+	if len(statements) > 1 {
+		head = joinStatements(statements[:len(statements)-1]) + "; "
+	}
+
+	limited := fmt.Sprintf("%sSELECT * FROM (%s) LIMIT %d", head, last.text, limit+1)
+	if start > 0 {
+		limited = fmt.Sprintf("%s START %d", limited, start)
+	}
+	return limited, true
+}
+
+// reports if the last statement orders its rows, without 'ORDER BY' the pages
+// of a paginated query can overlap or skip rows
+func limitOrdered(surql string) bool {
+	statements := splitStatements(surql)
+	if len(statements) == 0 {
+		return false
+	}
+
+	words := statements[len(statements)-1].words
+	for index := 0; index+1 < len(words); index++ {
+		if words[index] == "ORDER" && words[index+1] == "BY" {
+			return true
+		}
+	}
+	return false
+}
+
+// cuts the rows of an array result to the page, the start is skipped unless
+// the query was wrapped by 'limitQuery()', reports whether rows were cut
+func limitResult(result interface{}, limit int, start int, wrapped bool) (interface{}, bool) {
+	rows, isArray := result.([]interface{})
+	if isArray == false {
+		return result, false
+	}
+
+	if wrapped == false {
+		if start >= len(rows) {
+			rows = []interface{}{}
+		} else {
+			rows = rows[start:]
+		}
+	}
+
+	if limit > 0 && len(rows) > limit {
+		return rows[:limit], true
+	}
+	return rows, false
+}
+
+// reports a truncated result, the next page of a paginated query is reported
+// as information, otherwise the truncation is a warning
+func limitNotice(request queryRequestData, limit int, start int) data.Notice {
+	if request.Paginate {
+		return data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("Showing rows %d to %d, more rows are available with the cursor of the next page", start+1, start+limit),
+		}
+	}
+	return data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("The result was truncated to %d rows", limit),
+	}
+}
+
+// warns about the pagination of a query without order
+func orderNotice() data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     "The query has no ORDER BY clause, the pages may overlap or skip rows",
+	}
+}
@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestCursor(t *testing.T) {
+	for _, start := range []int{0, 1, 1000, 123456} {
+		decoded, err := decodeCursor(encodeCursor(start))
+		if err != nil || decoded != start {
+			t.Errorf("%d: got %d (%v)", start, decoded, err)
+		}
+	}
+
+	if start, err := decodeCursor(""); err != nil || start != 0 {
+		t.Errorf("expected the first page without a cursor, got %d (%v)", start, err)
+	}
+
+	for _, cursor := range []string{"!", "bm90IGpzb24", "eyJzdGFydCI6LTF9"} {
+		if _, err := decodeCursor(cursor); err == nil {
+			t.Errorf("%s: expected an invalid cursor", cursor)
+		}
+	}
+}
+
+func TestRowLimit(t *testing.T) {
+	tests := []struct {
+		maxRows  int
+		limit    int
+		paginate bool
+		expected int
+	}{
+		{0, 0, false, 0},
+		{0, 50, false, 50},
+		{100, 0, false, 100},
+		{100, 50, false, 50},
+		{100, 500, false, 100},
+		{0, 0, true, defaultPageSize},
+		{100, 0, true, 100},
+		{0, 20, true, 20},
+	}
+
+	for _, test := range tests {
+		request := queryRequestData{Limit: test.limit, Paginate: test.paginate}
+		if limit := rowLimit(test.maxRows, request); limit != test.expected {
+			t.Errorf("%v: expected %d, got %d", test, test.expected, limit)
+		}
+	}
+}
+
+func TestLimitQuery(t *testing.T) {
+	tests := []struct {
+		surql    string
+		limit    int
+		start    int
+		expected string
+		wrapped  bool
+	}{
+		{"select * from events", 100, 0, "SELECT * FROM (select * from events) LIMIT 101", true},
+		{"SELECT * FROM events ORDER BY time DESC;\n", 10, 20, "SELECT * FROM (SELECT * FROM events ORDER BY time DESC) LIMIT 11 START 20", true},
+		{"let $x = 1; select * from events where n > $x", 5, 0, "let $x = 1; SELECT * FROM (select * from events where n > $x) LIMIT 6", true},
+		{"info for db", 100, 0, "info for db", false},
+		{"select * from events; return 1", 100, 0, "select * from events; return 1", false},
+		{"select * from events where name = 'a;b'", 10, 0, "SELECT * FROM (select * from events where name = 'a;b') LIMIT 11", true},
+		{"select * from events -- latest", 10, 0, "SELECT * FROM (select * from events) LIMIT 11", true},
+		{"let $x = '; select'; info for db", 10, 0, "let $x = '; select'; info for db", false},
+		{"", 10, 0, "", false},
+	}
+
+	for _, test := range tests {
+		result, wrapped := limitQuery(test.surql, test.limit, test.start)
+		if result != test.expected || wrapped != test.wrapped {
+			t.Errorf("%s: expected '%s' (%v), got '%s' (%v)", test.surql, test.expected, test.wrapped, result, wrapped)
+		}
+	}
+}
+
+func TestLimitOrdered(t *testing.T) {
+	tests := map[string]bool{
+		"select * from events order by time":              true,
+		"SELECT * FROM events ORDER BY time DESC LIMIT 5": true,
+		"select * from events":                            false,
+		"select * from events where name = 'order by'":    false,
+		"select * from events order by time; return 1":    false,
+		"": false,
+	}
+
+	for surql, expected := range tests {
+		if limitOrdered(surql) != expected {
+			t.Errorf("'%s': expected ordered %v", surql, expected)
+		}
+	}
+}
+
+func TestLimitResult(t *testing.T) {
+	rows := []interface{}{"a", "b", "c", "d", "e"}
+
+	tests := []struct {
+		limit     int
+		start     int
+		wrapped   bool
+		expected  int
+		first     interface{}
+		truncated bool
+	}{
+		{0, 0, false, 5, "a", false},
+		{5, 0, false, 5, "a", false},
+		{3, 0, false, 3, "a", true},
+		{2, 2, false, 2, "c", true},
+		{2, 4, false, 1, "e", false},
+		{2, 9, false, 0, nil, false},
+		{4, 2, true, 4, "a", true},
+	}
+
+	for _, test := range tests {
+		result, truncated := limitResult(rows, test.limit, test.start, test.wrapped)
+		page := result.([]interface{})
+		if len(page) != test.expected || truncated != test.truncated {
+			t.Errorf("%v: expected %d rows (%v), got %d (%v)", test, test.expected, test.truncated, len(page), truncated)
+			continue
+		}
+		if len(page) > 0 && page[0] != test.first {
+			t.Errorf("%v: expected the first row '%v', got '%v'", test, test.first, page[0])
+		}
+	}
+
+	if result, truncated := limitResult("value", 1, 0, false); result != "value" || truncated {
+		t.Errorf("expected a scalar result to be kept")
+	}
+}
+
+func TestLimitNotice(t *testing.T) {
+	notice := limitNotice(queryRequestData{}, 100, 0)
+	if notice.Severity != data.NoticeSeverityWarning || notice.Text != "The result was truncated to 100 rows" {
+		t.Errorf("unexpected notice '%v'", notice)
+	}
+
+	notice = limitNotice(queryRequestData{Paginate: true}, 100, 200)
+	if notice.Severity != data.NoticeSeverityInfo || notice.Text != "Showing rows 201 to 300, more rows are available with the cursor of the next page" {
+		t.Errorf("unexpected notice '%v'", notice)
+	}
+}
+
+func TestQueryMaxRows(t *testing.T) {
+	records := []interface{}{}
+	for index := 0; index < 5; index++ {
+		records = append(records, map[string]interface{}{
+			"timestamp": time.Unix(int64(index), 0).UTC().Format(time.RFC3339),
+			"value":     index,
+		})
+	}
+	ds := testDatasource(t, sqlResult("1ms", records), configuration{MaxRows: 3})
+
+	response, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{{
+			RefID: "A",
+			// the pagination does not apply to metric queries
+			JSON: []byte(`{"mode":"metric","surql":"select * from metric","timestamp":"timestamp","metricData":"value","paginate":true}`),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataResponse := response.Responses["A"]
+	if dataResponse.Error != nil {
+		t.Fatal(dataResponse.Error)
+	}
+	if len(dataResponse.Frames) != 1 {
+		t.Fatalf("expected a single frame, got %d", len(dataResponse.Frames))
+	}
+
+	frame := dataResponse.Frames[0]
+	if frame.Rows() != 3 {
+		t.Errorf("expected the rows to be limited to 3, got %d", frame.Rows())
+	}
+	if strings.HasSuffix(frame.Meta.ExecutedQueryString, "LIMIT 4") == false {
+		t.Errorf("expected the query to be limited, got '%s'", frame.Meta.ExecutedQueryString)
+	}
+	if len(frame.Meta.Notices) != 1 || frame.Meta.Notices[0].Text != "The result was truncated to 3 rows" {
+		t.Errorf("expected the truncation warning, got '%+v'", frame.Meta.Notices)
+	}
+}
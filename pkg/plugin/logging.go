@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const redacted = "***"
+
+// string literals following a secret keyword, e.g. 'PASSWORD "..."' or
+// 'pass = "..."', are redacted in the debug logs
+var secretLiteral = regexp.MustCompile(`(?i)\b(password|passhash|pass|token|secret|key)\b(\s*[:=]?\s*)('[^']*'|"[^"]*")`)
+
+// adds the datasource, organization and user of the request to the context,
+// which the SDK logger returns by 'FromContext()'
+func withRequestLogContext(ctx context.Context, pCtx backend.PluginContext) context.Context {
+	attributes := []any{"orgId", pCtx.OrgID}
+	if pCtx.DataSourceInstanceSettings != nil {
+		attributes = append(attributes, "datasourceUid", pCtx.DataSourceInstanceSettings.UID)
+	}
+	if pCtx.User != nil {
+		attributes = append(attributes, "user", pCtx.User.Login)
+	}
+	return log.WithContextualAttributes(ctx, attributes)
+}
+
+// adds the RefID and the hash of the SurQL of a query to the context
+func withQueryLogContext(ctx context.Context, refID string, surql string) context.Context {
+	return log.WithContextualAttributes(ctx, []any{"refId", refID, "queryHash", queryHash(surql)})
+}
+
+func queryHash(surql string) string {
+	digest := sha256.Sum256([]byte(surql))
+	return hex.EncodeToString(digest[:])[:12]
+}
+
+func logger(ctx context.Context) log.Logger {
+	return log.DefaultLogger.FromContext(ctx)
+}
+
+// returns the logger of background work without a request, like the health
+// checks of the pool, which is labeled by the datasource
+func datasourceLogger(uid string) log.Logger {
+	return log.DefaultLogger.With("datasourceUid", uid)
+}
+
+// returns the logger of the query, which is not set for queries built in tests
+func queryLogger(query *queryData) log.Logger {
+	if query.logger == nil {
+		return log.DefaultLogger
+	}
+	return query.logger
+}
+
+// replaces the configured secrets and secret literals in the text, scope
+// parameters are only secret if they are configured in the secure JSON data,
+// forwarded user parameters like the login are kept
+func redact(config configuration, text string) string {
+	secrets := []string{config.Password, config.Token, config.TLSClientKey}
+	for _, name := range config.SecretScopeParameters {
+		if secret, isString := config.ScopeParameters[name].(string); isString {
+			secrets = append(secrets, secret)
+		}
+	}
+
+	for _, secret := range secrets {
+		// short values would redact unrelated parts of the text
+		if len(secret) >= 4 {
+			text = strings.ReplaceAll(text, secret, redacted)
+		}
+	}
+
+	return secretLiteral.ReplaceAllString(text, "$1$2'"+redacted+"'")
+}
+
+// describes the name, rows and field types of the frames for the debug logs
+func frameShapes(frames data.Frames) []string {
+	shapes := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		fields := make([]string, 0, len(frame.Fields))
+		for _, field := range frame.Fields {
+			fields = append(fields, fmt.Sprintf("%s:%s", field.Name, field.Type().ItemTypeString()))
+		}
+		shapes = append(shapes, fmt.Sprintf("%s[%d](%s)", frame.Name, frame.Rows(), strings.Join(fields, ", ")))
+	}
+	return shapes
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestRedact(t *testing.T) {
+	config := configuration{
+		Password:        "s3cret-password",
+		Token:           "eyJhbGciOi",
+		ScopeParameters: map[string]interface{}{"apiKey": "tenant-key", "short": "ab", "login": "viewer"},
+		// the login is a forwarded user parameter, not a secret
+		SecretScopeParameters: []string{"apiKey", "short"},
+	}
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"select * from metric", "select * from metric"},
+		{"select * from user where pass = 's3cret-password'", "select * from user where pass = '***'"},
+		{"define user viewer on database password 'hunter2'", "define user viewer on database password '***'"},
+		{`return { token: "abc" }`, `return { token: '***' }`},
+		{"signin failed for eyJhbGciOi and tenant-key", "signin failed for *** and ***"},
+		{"select ab from table", "select ab from table"},
+		{"select * from user where login = $login and name = 'viewer'", "select * from user where login = $login and name = 'viewer'"},
+	}
+
+	for _, test := range tests {
+		result := redact(config, test.text)
+		if result != test.expected {
+			t.Errorf("%s: expected '%s', got '%s'", test.text, test.expected, result)
+		}
+	}
+}
+
+func TestLogContext(t *testing.T) {
+	ctx := withRequestLogContext(context.Background(), backend.PluginContext{
+		OrgID:                      2,
+		User:                       &backend.User{Login: "viewer"},
+		DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{UID: "abc"},
+	})
+	ctx = withQueryLogContext(ctx, "A", "select * from metric")
+
+	expected := []any{"orgId", int64(2), "datasourceUid", "abc", "user", "viewer", "refId", "A", "queryHash", queryHash("select * from metric")}
+	if attributes := log.ContextualAttributesFromContext(ctx); reflect.DeepEqual(attributes, expected) == false {
+		t.Errorf("expected '%v', got '%v'", expected, attributes)
+	}
+
+	if hash := queryHash("select * from metric"); len(hash) != 12 || hash == queryHash("select * from log") {
+		t.Errorf("unexpected query hash '%s'", hash)
+	}
+}
+
+func TestFrameShapes(t *testing.T) {
+	frames := data.Frames{
+		data.NewFrame("A",
+			data.NewField("timestamp", nil, []*time.Time{nil, nil}),
+			data.NewField("value", nil, []*float64{nil, nil}),
+		),
+	}
+
+	expected := []string{"A[2](timestamp:*time.Time, value:*float64)"}
+	if shapes := frameShapes(frames); reflect.DeepEqual(shapes, expected) == false {
+		t.Errorf("expected '%v', got '%v'", expected, shapes)
+	}
+}
@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const groupOtherName = "other"
+
+// sums the values of equal timestamps in the order of their first record,
+// a timestamp with only null values remains null
+func groupFold(times []*time.Time, values []*float64) ([]*time.Time, []*float64) {
+	foldedTime := []*time.Time{}
+	foldedData := []*float64{}
+	indices := map[int64]int{}
+
+	for index, timestamp := range times {
+		if timestamp == nil {
+			foldedTime = append(foldedTime, nil)
+			foldedData = append(foldedData, values[index])
+			continue
+		}
+
+		position, exists := indices[timestamp.UnixNano()]
+		if exists == false {
+			indices[timestamp.UnixNano()] = len(foldedTime)
+			foldedTime = append(foldedTime, timestamp)
+			foldedData = append(foldedData, nil)
+			position = len(foldedTime) - 1
+		}
+
+		if values[index] == nil {
+			continue
+		}
+		if foldedData[position] == nil {
+			sum := *values[index]
+			foldedData[position] = &sum
+		} else {
+			*foldedData[position] += *values[index]
+		}
+	}
+
+	return foldedTime, foldedData
+}
+
+// selects the groups to keep according to the 'topK' or 'bottomK' request,
+// returns nil if no group limiting is requested
+func groupLimit(query *queryData, groupDataMap map[string][]*float64) (map[string]bool, error) {
+	topK := query.request.TopK
+	bottomK := query.request.BottomK
+
+	if topK < 0 || bottomK < 0 {
+		return nil, fmt.Errorf("invalid group limit, 'topK' and 'bottomK' must not be negative")
+	}
+	if topK > 0 && bottomK > 0 {
+		return nil, fmt.Errorf("invalid group limit, 'topK' and 'bottomK' are mutually exclusive")
+	}
+	if topK == 0 && bottomK == 0 {
+		return nil, nil
+	}
+
+	rankBy := query.request.GroupRankBy
+	if rankBy == "" {
+		rankBy = "sum"
+	}
+
+	keys := make([]string, 0, len(groupDataMap))
+	ranks := make(map[string]float64, len(groupDataMap))
+	for key, values := range groupDataMap {
+		rank, err := groupAggregate(rankBy, values)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+		ranks[key] = rank
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if ranks[keys[i]] == ranks[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		if topK > 0 {
+			return ranks[keys[i]] > ranks[keys[j]]
+		}
+		return ranks[keys[i]] < ranks[keys[j]]
+	})
+
+	limit := topK + bottomK
+	if limit > len(keys) {
+		limit = len(keys)
+	}
+
+	selected := make(map[string]bool, limit)
+	for _, key := range keys[:limit] {
+		selected[key] = true
+	}
+
+	return selected, nil
+}
+
+func groupAggregate(aggregate string, values []*float64) (float64, error) {
+	switch aggregate {
+	case "sum", "avg", "max", "last":
+	default:
+		return 0, fmt.Errorf("unsupported group rank aggregate '%s'", aggregate)
+	}
+
+	result := float64(0)
+	count := 0
+
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+
+		switch aggregate {
+		case "sum", "avg":
+			result = result + *value
+		case "max":
+			if count == 0 || *value > result {
+				result = *value
+			}
+		case "last":
+			result = *value
+		}
+
+		count++
+	}
+
+	if aggregate == "avg" && count > 0 {
+		result = result / float64(count)
+	}
+
+	return result, nil
+}
+
+// orders the group keys, which are given in order of their first appearance,
+// according to the 'groupOrder' request, by default the keys are sorted
+func groupOrder(query *queryData, keys []string, groupDataMap map[string][]*float64) ([]string, error) {
+	ordered := make([]string, len(keys))
+	copy(ordered, keys)
+
+	switch query.request.GroupOrder {
+	case "", "key":
+		sort.Strings(ordered)
+	case "appearance":
+	case "value":
+		rankBy := query.request.GroupRankBy
+		if rankBy == "" {
+			rankBy = "sum"
+		}
+
+		ranks := make(map[string]float64, len(ordered))
+		for _, key := range ordered {
+			rank, err := groupAggregate(rankBy, groupDataMap[key])
+			if err != nil {
+				return nil, err
+			}
+			ranks[key] = rank
+		}
+
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if ranks[ordered[i]] == ranks[ordered[j]] {
+				return ordered[i] < ordered[j]
+			}
+			return ranks[ordered[i]] > ranks[ordered[j]]
+		})
+	default:
+		return nil, fmt.Errorf("unsupported group order '%s'", query.request.GroupOrder)
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestGroupLimit(t *testing.T) {
+
+	groupDataMap := map[string][]*float64{
+		"a": {floatPointer(1), floatPointer(9)},
+		"b": {floatPointer(5), floatPointer(4)},
+		"c": {floatPointer(2), nil},
+	}
+
+	tests := []struct {
+		request  queryRequestData
+		expected []string
+	}{
+		{queryRequestData{TopK: 1}, []string{"a"}},
+		{queryRequestData{TopK: 2, GroupRankBy: "last"}, []string{"a", "b"}},
+		{queryRequestData{BottomK: 1, GroupRankBy: "max"}, []string{"c"}},
+		{queryRequestData{BottomK: 5, GroupRankBy: "avg"}, []string{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+		selected, err := groupLimit(&queryData{request: test.request}, groupDataMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(selected) != len(test.expected) {
+			t.Errorf("%+v: expected %v, got %v", test.request, test.expected, selected)
+		}
+		for _, key := range test.expected {
+			if selected[key] == false {
+				t.Errorf("%+v: expected group '%s' to be selected", test.request, key)
+			}
+		}
+	}
+
+	_, err := groupLimit(&queryData{request: queryRequestData{TopK: 1, BottomK: 1}}, groupDataMap)
+	if err == nil {
+		t.Error("'topK' and 'bottomK' must be mutually exclusive")
+	}
+}
+
+func TestMetricGroupOrder(t *testing.T) {
+
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A",
+			data.NewField("timestamp", nil, []*time.Time{unixTime(1), unixTime(2), unixTime(3), unixTime(4), unixTime(5)}),
+			data.NewField("value", nil, []*float64{floatPointer(1), floatPointer(7), floatPointer(3), floatPointer(2), floatPointer(5)}),
+			data.NewField("group", nil, []string{"c", "a", "b", "c", "d"}),
+		)
+	}
+
+	tests := []struct {
+		request  queryRequestData
+		expected []string
+	}{
+		{queryRequestData{}, []string{"a", "b", "c", "d"}},
+		{queryRequestData{GroupOrder: "key"}, []string{"a", "b", "c", "d"}},
+		{queryRequestData{GroupOrder: "appearance"}, []string{"c", "a", "b", "d"}},
+		{queryRequestData{GroupOrder: "value"}, []string{"a", "d", "b", "c"}},
+		{queryRequestData{GroupOrder: "value", GroupRankBy: "max"}, []string{"a", "d", "b", "c"}},
+		{queryRequestData{GroupOrder: "appearance", TopK: 2, GroupOther: true}, []string{"a", "d", "other"}},
+	}
+
+	ds := Datasource{}
+
+	for _, test := range tests {
+		// repeat, since an unordered grouping would randomly pass once
+		for run := 0; run < 10; run++ {
+			dataResponse := backend.DataResponse{Frames: []*data.Frame{newFrame()}}
+
+			err := ds.metricGroup(&queryData{request: test.request}, &dataResponse)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := []string{}
+			for _, frame := range dataResponse.Frames {
+				names = append(names, frame.Name)
+			}
+
+			if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+				t.Fatalf("%+v: expected %v, got %v", test.request, test.expected, names)
+			}
+		}
+	}
+
+	dataResponse := backend.DataResponse{Frames: []*data.Frame{newFrame()}}
+	err := ds.metricGroup(&queryData{request: queryRequestData{GroupOrder: "unknown"}}, &dataResponse)
+	if err == nil {
+		t.Error("unsupported group order must fail")
+	}
+}
+
+func TestMetricGroupOther(t *testing.T) {
+
+	newFrame := func(groups ...string) *data.Frame {
+		return data.NewFrame("A",
+			data.NewField("timestamp", nil, []*time.Time{unixTime(1), unixTime(1), unixTime(1), unixTime(2), unixTime(2), unixTime(2)}),
+			data.NewField("value", nil, []*float64{floatPointer(9), floatPointer(1), floatPointer(2), floatPointer(9), floatPointer(3), nil}),
+			data.NewField("group", nil, groups),
+		)
+	}
+
+	ds := Datasource{}
+	request := queryRequestData{TopK: 1, GroupOther: true}
+
+	// a real group 'other' outside of the top-K is not emitted twice
+	dataResponse := backend.DataResponse{Frames: []*data.Frame{newFrame("a", "other", "c", "a", "other", "c")}}
+	err := ds.metricGroup(&queryData{request: request}, &dataResponse)
+	if err == nil {
+		t.Errorf("expected a conflict with the group '%s', got %d frames", groupOtherName, len(dataResponse.Frames))
+	}
+
+	// the folded groups are summed per timestamp
+	dataResponse = backend.DataResponse{Frames: []*data.Frame{newFrame("a", "b", "c", "a", "b", "c")}}
+	err = ds.metricGroup(&queryData{request: request}, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dataResponse.Frames) != 2 || dataResponse.Frames[1].Name != groupOtherName {
+		t.Fatalf("expected the frames 'a' and '%s', got %d", groupOtherName, len(dataResponse.Frames))
+	}
+
+	other := dataResponse.Frames[1]
+	if other.Rows() != 2 {
+		t.Fatalf("expected 2 timestamps, got %d", other.Rows())
+	}
+	for index, expected := range []float64{3, 3} {
+		if sum := other.Fields[1].At(index).(*float64); sum == nil || *sum != expected {
+			t.Errorf("%d: expected the sum %v, got %v", index, expected, sum)
+		}
+	}
+
+	// with rate the records of the folded groups are kept
+	request.Rate = true
+	dataResponse = backend.DataResponse{Frames: []*data.Frame{newFrame("a", "b", "c", "a", "b", "c")}}
+	err = ds.metricGroup(&queryData{request: request}, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := dataResponse.Frames[1].Rows(); rows != 4 {
+		t.Errorf("expected 4 records with rate, got %d", rows)
+	}
+}
+
+func TestGroupFold(t *testing.T) {
+	t1 := time.Unix(1, 0)
+	t2 := time.Unix(2, 0)
+
+	times, values := groupFold(
+		[]*time.Time{&t2, &t1, &t2, nil, &t1},
+		[]*float64{floatPointer(1), nil, floatPointer(2), floatPointer(5), nil},
+	)
+
+	if len(times) != 3 || *times[0] != t2 || *times[1] != t1 || times[2] != nil {
+		t.Fatalf("unexpected timestamps %v", times)
+	}
+	if *values[0] != 3 || values[1] != nil || *values[2] != 5 {
+		t.Errorf("unexpected sums %v", values)
+	}
+}
@@ -0,0 +1,195 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+type queryTransformData struct {
+	Function string  `json:"function"`
+	Window   int     `json:"window"`
+	Alpha    float64 `json:"alpha"`
+}
+
+const (
+	defaultTransformWindow = 5
+	defaultTransformAlpha  = 0.5
+)
+
+// applies the ordered list of transforms to every value field of the frame,
+// the time field is expected at index 0 (see 'metric()' and 'metricRate()')
+func (r *Datasource) metricTransform(query *queryData, frame *data.Frame) error {
+	if len(frame.Fields) == 0 {
+		return nil
+	}
+
+	timeField := frame.Fields[0]
+
+	times := make([]*time.Time, timeField.Len())
+	for index := range times {
+		times[index] = fieldTime(timeField, index)
+	}
+
+	for fieldIndex, field := range frame.Fields[1:] {
+		values := make([]*float64, field.Len())
+		for index := range values {
+			value, err := field.NullableFloatAt(index)
+			if err != nil {
+				return fmt.Errorf("field '%s' is not numeric: %w", field.Name, err)
+			}
+			values[index] = value
+		}
+
+		for _, transform := range query.request.Transforms {
+			var err error
+			values, err = transformValues(transform, times, values)
+			if err != nil {
+				return err
+			}
+		}
+
+		transformField := data.NewField(field.Name, field.Labels, values)
+		transformField.Config = field.Config
+		frame.Fields[fieldIndex+1] = transformField
+	}
+
+	return nil
+}
+
+func transformValues(transform queryTransformData, times []*time.Time, values []*float64) ([]*float64, error) {
+	switch transform.Function {
+	case "movingAverage":
+		window := transform.Window
+		if window == 0 {
+			window = defaultTransformWindow
+		}
+		if window < 0 {
+			return nil, fmt.Errorf("invalid moving average window '%d'", window)
+		}
+		return movingAverage(values, window), nil
+	case "ewma":
+		alpha := transform.Alpha
+		if alpha == 0 {
+			alpha = defaultTransformAlpha
+		}
+		if alpha < 0 || alpha > 1 {
+			return nil, fmt.Errorf("invalid ewma alpha '%v', expected a value in (0, 1]", alpha)
+		}
+		return exponentialMovingAverage(values, alpha), nil
+	case "cumulativeSum":
+		return cumulativeSum(values), nil
+	case "derivative":
+		return derivative(times, values, false), nil
+	case "nonNegativeDerivative":
+		return derivative(times, values, true), nil
+	default:
+		return nil, fmt.Errorf("unsupported transform function '%s'", transform.Function)
+	}
+}
+
+func fieldTime(field *data.Field, index int) *time.Time {
+	switch value := field.At(index).(type) {
+	case time.Time:
+		return &value
+	case *time.Time:
+		return value
+	default:
+		return nil
+	}
+}
+
+func movingAverage(values []*float64, window int) []*float64 {
+	result := make([]*float64, len(values))
+
+	for index := range values {
+		if values[index] == nil {
+			continue
+		}
+
+		start := index - window + 1
+		if start < 0 {
+			start = 0
+		}
+
+		total := float64(0)
+		count := 0
+		for _, value := range values[start : index+1] {
+			if value != nil {
+				total = total + *value
+				count++
+			}
+		}
+
+		current := total / float64(count)
+		result[index] = &current
+	}
+
+	return result
+}
+
+func exponentialMovingAverage(values []*float64, alpha float64) []*float64 {
+	result := make([]*float64, len(values))
+
+	var previous *float64
+	for index, value := range values {
+		if value == nil {
+			continue
+		}
+
+		current := *value
+		if previous != nil {
+			current = alpha*(*value) + (1-alpha)*(*previous)
+		}
+
+		previous = &current
+		result[index] = &current
+	}
+
+	return result
+}
+
+func cumulativeSum(values []*float64) []*float64 {
+	result := make([]*float64, len(values))
+
+	total := float64(0)
+	for index, value := range values {
+		if value == nil {
+			continue
+		}
+
+		total = total + *value
+
+		current := total
+		result[index] = &current
+	}
+
+	return result
+}
+
+// computes the per-second change between consecutive non-null values
+func derivative(times []*time.Time, values []*float64, nonNegative bool) []*float64 {
+	result := make([]*float64, len(values))
+
+	previous := -1
+	for index, value := range values {
+		if value == nil || times[index] == nil {
+			continue
+		}
+
+		if previous >= 0 {
+			seconds := times[index].Sub(*times[previous]).Seconds()
+			if seconds > 0 {
+				delta := (*value - *values[previous]) / seconds
+				if nonNegative == false || delta >= 0 {
+					result[index] = &delta
+				}
+			}
+		}
+
+		previous = index
+	}
+
+	return result
+}
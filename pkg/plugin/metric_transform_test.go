@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestTransformValues(t *testing.T) {
+	times := []*time.Time{unixTime(0), unixTime(10), unixTime(20), unixTime(30)}
+
+	values := []*float64{floatPointer(10), floatPointer(30), nil, floatPointer(20)}
+
+	tests := []struct {
+		transform queryTransformData
+		expected  []*float64
+	}{
+		{queryTransformData{Function: "movingAverage", Window: 2}, []*float64{floatPointer(10), floatPointer(20), nil, floatPointer(20)}},
+		{queryTransformData{Function: "ewma", Alpha: 0.5}, []*float64{floatPointer(10), floatPointer(20), nil, floatPointer(20)}},
+		{queryTransformData{Function: "cumulativeSum"}, []*float64{floatPointer(10), floatPointer(40), nil, floatPointer(60)}},
+		{queryTransformData{Function: "derivative"}, []*float64{nil, floatPointer(2), nil, floatPointer(-0.5)}},
+		{queryTransformData{Function: "nonNegativeDerivative"}, []*float64{nil, floatPointer(2), nil, nil}},
+	}
+
+	for _, test := range tests {
+		result, err := transformValues(test.transform, times, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for index := range test.expected {
+			expected, actual := test.expected[index], result[index]
+			if (expected == nil) != (actual == nil) || (expected != nil && *expected != *actual) {
+				t.Errorf("%s: unexpected value at %d", test.transform.Function, index)
+			}
+		}
+	}
+
+	_, err := transformValues(queryTransformData{Function: "unknown"}, times, values)
+	if err == nil {
+		t.Error("unsupported transform function must fail")
+	}
+}
+
+func TestMetricTransform(t *testing.T) {
+
+	ds := Datasource{}
+
+	// the null values are kept and skipped by the derivative
+	valueField := data.NewField("value", data.Labels{"host": "a"}, []*float64{floatPointer(10), nil, floatPointer(20)})
+	valueField.Config = &data.FieldConfig{Unit: "ms"}
+	frame := data.NewFrame("A",
+		data.NewField("timestamp", nil, []*time.Time{unixTime(0), unixTime(10), unixTime(20)}),
+		valueField,
+	)
+
+	query := &queryData{request: queryRequestData{Transforms: []queryTransformData{{Function: "derivative"}}}}
+	err := ds.metricTransform(query, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	field := frame.Fields[1]
+	if field.Name != "value" || field.Labels["host"] != "a" || field.Config == nil || field.Config.Unit != "ms" {
+		t.Errorf("expected the name, labels and config to be kept, got '%v'", field)
+	}
+	for index, expected := range []*float64{nil, nil, floatPointer(0.5)} {
+		actual, _ := field.NullableFloatAt(index)
+		if (expected == nil) != (actual == nil) || (expected != nil && *expected != *actual) {
+			t.Errorf("derivative: unexpected value at %d", index)
+		}
+	}
+
+	// the empty rate buckets are null, unless the zero vector is enabled
+	tests := []struct {
+		rateZero bool
+		expected []*float64
+	}{
+		{false, []*float64{floatPointer(2), nil, floatPointer(3), nil}},
+		{true, []*float64{floatPointer(2), floatPointer(2), floatPointer(3), floatPointer(3)}},
+	}
+
+	for _, test := range tests {
+		frame := data.NewFrame("A",
+			data.NewField("timestamp", nil, []*time.Time{unixTime(1), unixTime(2), unixTime(25)}),
+			data.NewField("value", nil, []*float64{floatPointer(5), nil, floatPointer(3)}),
+		)
+
+		query := &queryData{
+			request: queryRequestData{
+				RateZero:      test.rateZero,
+				RateInterval:  "10s",
+				RateFunctions: []string{"count"},
+				Transforms:    []queryTransformData{{Function: "cumulativeSum"}},
+			},
+			timeFrom: time.Unix(0, 0),
+			timeTo:   time.Unix(30, 0),
+		}
+
+		err := ds.metricRate(query, frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ds.metricTransform(query, frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(frame.Fields) != 2 || frame.Fields[1].Name != "count" {
+			t.Fatalf("unexpected rate fields '%v'", frame.Fields)
+		}
+		field := frame.Fields[1]
+		if field.Len() != len(test.expected) {
+			t.Fatalf("rate zero %v: expected %d buckets, got %d", test.rateZero, len(test.expected), field.Len())
+		}
+		for index, expected := range test.expected {
+			actual, _ := field.NullableFloatAt(index)
+			if (expected == nil) != (actual == nil) || (expected != nil && *expected != *actual) {
+				t.Errorf("rate zero %v: unexpected value at %d", test.rateZero, index)
+			}
+		}
+	}
+
+	text := data.NewFrame("A",
+		data.NewField("timestamp", nil, []*time.Time{unixTime(0)}),
+		data.NewField("host", nil, []string{"a"}),
+	)
+	err = ds.metricTransform(query, text)
+	if err == nil {
+		t.Error("non-numeric field must fail")
+	}
+}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "surrealdb_datasource"
+
+// the collectors are registered with the default registry, which is the
+// mechanism of the plugin metrics, the SDK gathers 'prometheus.DefaultGatherer'
+// for its diagnostics handler, so the datasource does not implement
+// 'backend.CollectMetricsHandler', all series are labeled by the datasource UID
+var (
+	metricQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "queries_total",
+		Help:      "Number of queries by mode and status.",
+	}, []string{"datasource_uid", "mode", "status"})
+
+	metricQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "query_duration_seconds",
+		Help:      "Duration of queries including processing by mode and status.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"datasource_uid", "mode", "status"})
+
+	metricQueriesInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "queries_in_flight",
+		Help:      "Number of queries currently running.",
+	}, []string{"datasource_uid", "mode"})
+
+	metricRPCErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rpc_errors_total",
+		Help:      "Number of failed SurrealDB requests by kind (connection, timeout, rpc).",
+	}, []string{"datasource_uid", "kind"})
+
+	metricReconnects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconnects_total",
+		Help:      "Number of websocket reconnects by result (success, failure).",
+	}, []string{"datasource_uid", "result"})
+
+	metricFrameRows = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "frame_rows",
+		Help:      "Number of rows of the returned frames.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	}, []string{"datasource_uid", "mode"})
+
+	metricFrameFields = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "frame_fields",
+		Help:      "Number of fields of the returned frames.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 8),
+	}, []string{"datasource_uid", "mode"})
+
+	metricRateBuckets = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_buckets",
+		Help:      "Number of time buckets of rate computations.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	}, []string{"datasource_uid"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricQueries,
+		metricQueryDuration,
+		metricQueriesInFlight,
+		metricRPCErrors,
+		metricReconnects,
+		metricFrameRows,
+		metricFrameFields,
+		metricRateBuckets,
+	)
+}
+
+// returns the mode label of a query, the mode is validated by 'queryData()'
+func queryModeLabel(dataQuery backend.DataQuery) string {
+	var request struct {
+		Mode string `json:"mode"`
+	}
+	err := json.Unmarshal(dataQuery.JSON, &request)
+	if err != nil {
+		return "unknown"
+	}
+
+	mode, err := NewQueryMode(request.Mode)
+	if err != nil {
+		return "unknown"
+	}
+	return mode.String()
+}
+
+// tracks a running query, the returned function records its response
+func (r *Datasource) observeQuery(mode string) func(response backend.DataResponse) {
+	uid := r.config.UID
+	start := time.Now()
+
+	inFlight := metricQueriesInFlight.WithLabelValues(uid, mode)
+	inFlight.Inc()
+
+	return func(response backend.DataResponse) {
+		inFlight.Dec()
+
+		status := "ok"
+		if response.Error != nil {
+			status = "error"
+		}
+
+		metricQueries.WithLabelValues(uid, mode, status).Inc()
+		metricQueryDuration.WithLabelValues(uid, mode, status).Observe(time.Since(start).Seconds())
+
+		for _, frame := range response.Frames {
+			metricFrameRows.WithLabelValues(uid, mode).Observe(float64(frame.Rows()))
+			metricFrameFields.WithLabelValues(uid, mode).Observe(float64(len(frame.Fields)))
+		}
+	}
+}
+
+func observeRPCError(uid string, err error) {
+	kind := "rpc"
+	switch {
+	case errors.Is(err, errConnection), errors.Is(err, errClosed):
+		kind = "connection"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		kind = "timeout"
+	}
+	metricRPCErrors.WithLabelValues(uid, kind).Inc()
+}
+
+func observeReconnect(uid string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	metricReconnects.WithLabelValues(uid, result).Inc()
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestQueryMetrics(t *testing.T) {
+	ds := testDatasource(t, sqlResult("1ms", []interface{}{
+		map[string]interface{}{"timestamp": "2024-01-01T00:00:00Z", "value": 1},
+		map[string]interface{}{"timestamp": "2024-01-01T00:01:00Z", "value": 2},
+	}), configuration{UID: "metrics-test"})
+
+	ctx := context.Background()
+	_, err := ds.QueryData(ctx, &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{
+			{RefID: "A", JSON: []byte(`{"mode":"metric","surql":"select * from metric"}`)},
+			{RefID: "B", JSON: []byte(`{"mode":"unknown"}`)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := testutil.ToFloat64(metricQueries.WithLabelValues("metrics-test", "metric", "ok")); count != 1 {
+		t.Errorf("expected 1 successful metric query, got %v", count)
+	}
+	if count := testutil.ToFloat64(metricQueries.WithLabelValues("metrics-test", "unknown", "error")); count != 1 {
+		t.Errorf("expected 1 failed unknown query, got %v", count)
+	}
+	if count := testutil.ToFloat64(metricQueriesInFlight.WithLabelValues("metrics-test", "metric")); count != 0 {
+		t.Errorf("expected no queries in flight, got %v", count)
+	}
+
+	// the SDK serves the metrics of the default registry
+	for _, name := range []string{
+		"surrealdb_datasource_queries_total",
+		"surrealdb_datasource_frame_rows",
+		"surrealdb_datasource_query_duration_seconds",
+	} {
+		count, err := testutil.GatherAndCount(prometheus.DefaultGatherer, name)
+		if err != nil || count == 0 {
+			t.Errorf("missing metric '%s' (%v)", name, err)
+		}
+	}
+}
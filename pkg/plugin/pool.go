@@ -0,0 +1,322 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPoolMinSize             = 1
+	defaultPoolMaxSize             = 10
+	defaultPoolIdleTimeout         = 5 * time.Minute
+	defaultPoolHealthCheckInterval = 30 * time.Second
+	poolHealthCheckTimeout         = 5 * time.Second
+	poolDrainTimeout               = 30 * time.Second
+)
+
+type poolOptions struct {
+	minSize             int
+	maxSize             int
+	idleTimeout         time.Duration
+	healthCheckInterval time.Duration
+}
+
+type pooledConnection struct {
+	db       *connection
+	lastUsed time.Time
+}
+
+// keeps between the minimum and maximum number of authenticated connections,
+// queries borrow a connection and wait if all connections are in use
+type pool struct {
+	location  string
+	tlsConfig *tls.Config
+	config    configuration
+	options   poolOptions
+
+	slots chan struct{}
+
+	mutex    sync.Mutex
+	idle     []*pooledConnection
+	borrowed sync.WaitGroup
+	closed   bool
+	stop     chan struct{}
+}
+
+func newPoolOptions(jsonData datasourceOptions) (poolOptions, error) {
+	options := poolOptions{
+		minSize:             defaultPoolMinSize,
+		maxSize:             defaultPoolMaxSize,
+		idleTimeout:         defaultPoolIdleTimeout,
+		healthCheckInterval: defaultPoolHealthCheckInterval,
+	}
+
+	if jsonData.PoolMinSize != nil {
+		options.minSize = *jsonData.PoolMinSize
+	}
+	if jsonData.PoolMaxSize != nil {
+		options.maxSize = *jsonData.PoolMaxSize
+	}
+	if options.minSize < 0 || options.maxSize < 1 || options.minSize > options.maxSize {
+		return options, fmt.Errorf(
+			"invalid pool size, expected 0 <= 'poolMinSize' (%d) <= 'poolMaxSize' (%d) and 'poolMaxSize' >= 1",
+			options.minSize,
+			options.maxSize,
+		)
+	}
+
+	if jsonData.PoolIdleTimeout != "" {
+		idleTimeout, err := time.ParseDuration(jsonData.PoolIdleTimeout)
+		if err != nil || idleTimeout <= 0 {
+			return options, fmt.Errorf("invalid pool idle timeout '%s'", jsonData.PoolIdleTimeout)
+		}
+		options.idleTimeout = idleTimeout
+	}
+
+	if jsonData.PoolHealthCheckInterval != "" {
+		healthCheckInterval, err := time.ParseDuration(jsonData.PoolHealthCheckInterval)
+		if err != nil || healthCheckInterval <= 0 {
+			return options, fmt.Errorf("invalid pool health check interval '%s'", jsonData.PoolHealthCheckInterval)
+		}
+		options.healthCheckInterval = healthCheckInterval
+	}
+
+	return options, nil
+}
+
+// opens the minimum number of connections, without a minimum size no
+// connection is opened until the first query
+func newPool(ctx context.Context, location string, tlsConfig *tls.Config, config configuration, options poolOptions) (*pool, error) {
+	p := &pool{
+		location:  location,
+		tlsConfig: tlsConfig,
+		config:    config,
+		options:   options,
+		slots:     make(chan struct{}, options.maxSize),
+		stop:      make(chan struct{}),
+	}
+
+	for index := 0; index < options.minSize; index++ {
+		db, err := newConnection(ctx, location, tlsConfig, config)
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.idle = append(p.idle, &pooledConnection{db: db, lastUsed: time.Now()})
+	}
+
+	go p.supervise()
+
+	return p, nil
+}
+
+func (p *pool) borrow(ctx context.Context) (*pooledConnection, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for a pool connection: %w", ctx.Err())
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		<-p.slots
+		return nil, errClosed
+	}
+
+	p.borrowed.Add(1)
+
+	if len(p.idle) > 0 {
+		entry := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mutex.Unlock()
+		return entry, nil
+	}
+	p.mutex.Unlock()
+
+	db, err := newConnection(ctx, p.location, p.tlsConfig, p.config)
+	if err != nil {
+		p.borrowed.Done()
+		<-p.slots
+		return nil, err
+	}
+
+	return &pooledConnection{db: db}, nil
+}
+
+func (p *pool) release(entry *pooledConnection) {
+	entry.lastUsed = time.Now()
+	p.restore(entry, -1)
+}
+
+// returns the connection to the idle connections at the index, or appends it
+// for a negative index, and frees its slot
+func (p *pool) restore(entry *pooledConnection, index int) {
+	p.mutex.Lock()
+	if p.closed {
+		entry.db.close()
+	} else if index < 0 || index >= len(p.idle) {
+		p.idle = append(p.idle, entry)
+	} else {
+		p.idle = append(p.idle, nil)
+		copy(p.idle[index+1:], p.idle[index:])
+		p.idle[index] = entry
+	}
+	p.mutex.Unlock()
+
+	p.borrowed.Done()
+	<-p.slots
+}
+
+// takes a slot without waiting, so that connections which are checked or
+// opened by the supervisor count against the maximum size
+func (p *pool) reserve() bool {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		return false
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		<-p.slots
+		return false
+	}
+	p.borrowed.Add(1)
+	return true
+}
+
+func (p *pool) unreserve() {
+	p.borrowed.Done()
+	<-p.slots
+}
+
+func (p *pool) query(ctx context.Context, query string) (interface{}, error) {
+	entry, err := p.borrow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(entry)
+
+	return entry.db.query(ctx, query)
+}
+
+// periodically closes idle connections above the minimum size, replaces
+// connections which fail the health check and refills the minimum size
+func (p *pool) supervise() {
+	ticker := time.NewTicker(p.options.healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.check()
+		}
+	}
+}
+
+// checks the idle connections one at a time in place, each checked
+// connection takes a slot like a borrowed one, the check stops if all slots
+// are in use because then there are no idle connections left to check
+func (p *pool) check() {
+	now := time.Now()
+
+	p.mutex.Lock()
+	count := len(p.idle)
+	p.mutex.Unlock()
+
+	index := 0
+	for checked := 0; checked < count; checked++ {
+		if p.reserve() == false {
+			break
+		}
+
+		p.mutex.Lock()
+		if index >= len(p.idle) {
+			p.mutex.Unlock()
+			p.unreserve()
+			break
+		}
+		entry := p.idle[index]
+		p.idle = append(p.idle[:index], p.idle[index+1:]...)
+		others := len(p.idle)
+		p.mutex.Unlock()
+
+		if now.Sub(entry.lastUsed) > p.options.idleTimeout && others >= p.options.minSize {
+			entry.db.close()
+			p.unreserve()
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), poolHealthCheckTimeout)
+		_, err := entry.db.query(ctx, "return true")
+		cancel()
+		if err != nil {
+			datasourceLogger(p.config.UID).Warn("Pool connection failed health check", "location", p.location, "error", err)
+			entry.db.close()
+			p.unreserve()
+			continue
+		}
+
+		p.restore(entry, index)
+		index++
+	}
+
+	for {
+		p.mutex.Lock()
+		size := len(p.idle)
+		p.mutex.Unlock()
+		if size >= p.options.minSize || p.reserve() == false {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), poolHealthCheckTimeout)
+		db, err := newConnection(ctx, p.location, p.tlsConfig, p.config)
+		cancel()
+		if err != nil {
+			datasourceLogger(p.config.UID).Warn("Pool connection refill failed", "location", p.location, "error", err)
+			p.unreserve()
+			return
+		}
+		p.restore(&pooledConnection{db: db, lastUsed: now}, -1)
+	}
+}
+
+// rejects new queries, waits for the borrowed connections to be returned and
+// closes all connections
+func (p *pool) close() {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.stop)
+
+	idle := p.idle
+	p.idle = nil
+	p.mutex.Unlock()
+
+	for _, entry := range idle {
+		entry.db.close()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		p.borrowed.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(poolDrainTimeout):
+		datasourceLogger(p.config.UID).Warn("Pool drain timed out, connections in use are closed when returned", "location", p.location)
+	}
+}
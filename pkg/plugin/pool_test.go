@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	server := newFakeSurrealDB(t, 0, nil)
+	location := server.location()
+	ctx := context.Background()
+
+	options := poolOptions{
+		minSize:             1,
+		maxSize:             3,
+		idleTimeout:         time.Minute,
+		healthCheckInterval: time.Minute,
+	}
+
+	p, err := newPool(ctx, location, nil, configuration{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wait := sync.WaitGroup{}
+	for index := 0; index < 20; index++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			_, err := p.query(ctx, "select * from metric")
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wait.Wait()
+
+	if server.signins.Load() > int32(options.maxSize) {
+		t.Errorf("expected at most %d connections, got %d", options.maxSize, server.signins.Load())
+	}
+
+	p.close()
+
+	_, err = p.query(ctx, "select * from metric")
+	if err == nil {
+		t.Error("closed pool must reject queries")
+	}
+}
+
+func TestPoolCheck(t *testing.T) {
+	server := newFakeSurrealDB(t, 0, nil)
+	location := server.location()
+	ctx := context.Background()
+
+	options := poolOptions{
+		minSize:             0,
+		maxSize:             1,
+		idleTimeout:         time.Minute,
+		healthCheckInterval: time.Hour,
+	}
+
+	// without a minimum size no connection is opened up front
+	p, err := newPool(ctx, location, nil, configuration{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.close()
+	if server.signins.Load() != 0 || len(p.idle) != 0 {
+		t.Fatalf("expected no connection, got %d", server.signins.Load())
+	}
+
+	_, err = p.query(ctx, "select * from metric")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a healthy connection is kept in place
+	p.check()
+	if server.signins.Load() != 1 || len(p.idle) != 1 {
+		t.Errorf("expected the checked connection to be kept, got %d idle and %d signins", len(p.idle), server.signins.Load())
+	}
+
+	// a query borrows the checked connection instead of opening another one
+	_, err = p.query(ctx, "select * from metric")
+	if err != nil || server.signins.Load() != 1 {
+		t.Errorf("expected the idle connection to be reused, got %d signins (%v)", server.signins.Load(), err)
+	}
+
+	// the check does not exceed the maximum size while connections are in use
+	entry, err := p.borrow(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.options.minSize = 1
+	p.check()
+	if server.signins.Load() != 1 {
+		t.Errorf("expected no connection above the maximum size, got %d signins", server.signins.Load())
+	}
+	p.release(entry)
+	p.options.minSize = 0
+
+	// idle connections above the minimum size are closed
+	p.idle[0].lastUsed = time.Now().Add(-time.Hour)
+	p.check()
+	if len(p.idle) != 0 {
+		t.Errorf("expected the idle connection to be closed, got %d", len(p.idle))
+	}
+}
+
+func TestNewPoolOptions(t *testing.T) {
+	size := func(v int) *int { return &v }
+
+	tests := []struct {
+		jsonData datasourceOptions
+		invalid  bool
+	}{
+		{datasourceOptions{}, false},
+		{datasourceOptions{PoolMinSize: size(0), PoolMaxSize: size(1)}, false},
+		{datasourceOptions{PoolMinSize: size(2), PoolMaxSize: size(1)}, true},
+		{datasourceOptions{PoolMaxSize: size(0)}, true},
+		{datasourceOptions{PoolIdleTimeout: "1m", PoolHealthCheckInterval: "10s"}, false},
+		{datasourceOptions{PoolIdleTimeout: "soon"}, true},
+	}
+
+	for _, test := range tests {
+		_, err := newPoolOptions(test.jsonData)
+		if (err != nil) != test.invalid {
+			t.Errorf("%+v: unexpected result '%v'", test.jsonData, err)
+		}
+	}
+}
@@ -8,6 +8,11 @@ const (
 	_RAW    = "raw"
 	_LOG    = "log"
 	_METRIC = "metric"
+
+	_HISTOGRAM = "histogram"
+	_EXPLAIN   = "explain"
+	_GRAPH     = "graph"
+	_TRACE     = "trace"
 )
 
 const (
@@ -15,6 +20,10 @@ const (
 	RawQueryMode
 	LogQueryMode
 	MetricQueryMode
+	HistogramQueryMode
+	ExplainQueryMode
+	GraphQueryMode
+	TraceQueryMode
 )
 
 func NewQueryMode(value string) (QueryMode, error) {
@@ -25,6 +34,14 @@ func NewQueryMode(value string) (QueryMode, error) {
 		return LogQueryMode, nil
 	case _METRIC:
 		return MetricQueryMode, nil
+	case _HISTOGRAM:
+		return HistogramQueryMode, nil
+	case _EXPLAIN:
+		return ExplainQueryMode, nil
+	case _GRAPH:
+		return GraphQueryMode, nil
+	case _TRACE:
+		return TraceQueryMode, nil
 	default:
 		return UndefinedQueryMode, fmt.Errorf("unsupported query mode '%s'", value)
 	}
@@ -38,6 +55,14 @@ func (r QueryMode) String() string {
 		return _LOG
 	case MetricQueryMode:
 		return _METRIC
+	case HistogramQueryMode:
+		return _HISTOGRAM
+	case ExplainQueryMode:
+		return _EXPLAIN
+	case GraphQueryMode:
+		return _GRAPH
+	case TraceQueryMode:
+		return _TRACE
 	default:
 		return "" // explicitly requested behavior by Grafana plugin reviewer
 	}
@@ -0,0 +1,282 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// this is the websocket RPC transport, it speaks the same protocol as the
+// SurrealDB client 'surrealdb.go' v0.2.1, which it replaces because the client
+// can not be supervised:
+//   - its read loop continues after a read error, gorilla/websocket panics
+//     the plugin process on repeated reads from a failed websocket
+//   - a broken websocket is not reported, so it can not be reconnected
+//   - it dials with the default dialer, without the TLS configuration of
+//     'newTLSConfig()', and without the context of the request
+//   - it only speaks JSON, SurrealDB 2.x types need the CBOR protocol
+//
+// https://docs.surrealdb.com/docs/integration/websocket
+
+const defaultRPCTimeout = 30 * time.Second
+
+var (
+	errConnection = errors.New("connection failed")
+	errClosed     = errors.New("connection closed")
+)
+
+type rpcRequest struct {
+	ID     string        `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     interface{} `json:"id"`
+	Result interface{} `json:"result"`
+	Error  *rpcError   `json:"error"`
+
+	// length of the received message
+	size int
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (r *rpcError) Error() string {
+	return r.Message
+}
+
+type rpcClient struct {
+	conn    *websocket.Conn
+	timeout time.Duration
+	cbor    bool
+
+	writeMutex sync.Mutex
+
+	responsesMutex sync.Mutex
+	responses      map[string]chan rpcResponse
+	requestID      atomic.Uint64
+
+	done      chan struct{}
+	doneOnce  sync.Once
+	doneError error
+}
+
+// SurrealDB 2.x negotiates the CBOR protocol, which keeps the types of
+// datetimes, durations, record IDs and decimals (see 'cborUnmarshal()')
+func newRPCClient(ctx context.Context, location string, tlsConfig *tls.Config, useCBOR bool) (*rpcClient, error) {
+	dialer := websocket.Dialer{
+		Proxy:             websocket.DefaultDialer.Proxy,
+		HandshakeTimeout:  websocket.DefaultDialer.HandshakeTimeout,
+		TLSClientConfig:   tlsConfig,
+		EnableCompression: true,
+	}
+	if useCBOR {
+		dialer.Subprotocols = []string{"cbor"}
+	}
+
+	conn, _, err := dialer.DialContext(ctx, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errConnection, err)
+	}
+
+	client := &rpcClient{
+		conn:      conn,
+		timeout:   defaultRPCTimeout,
+		cbor:      useCBOR,
+		responses: map[string]chan rpcResponse{},
+		done:      make(chan struct{}),
+	}
+
+	go client.read()
+
+	return client, nil
+}
+
+func (r *rpcClient) read() {
+	for {
+		_, message, err := r.conn.ReadMessage()
+		if err != nil {
+			r.fail(err)
+			return
+		}
+
+		var response rpcResponse
+		if r.cbor {
+			err = cborUnmarshal(message, &response)
+		} else {
+			err = json.Unmarshal(message, &response)
+		}
+		if err != nil {
+			r.decodeFailed(message, err)
+			continue
+		}
+
+		response.size = len(message)
+		r.deliver(response)
+	}
+}
+
+// passes the response to the pending request of its ID, responses of requests
+// which timed out are dropped
+func (r *rpcClient) deliver(response rpcResponse) {
+	id := fmt.Sprintf("%v", response.ID)
+
+	r.responsesMutex.Lock()
+	responseChannel, exists := r.responses[id]
+	delete(r.responses, id)
+	r.responsesMutex.Unlock()
+
+	if exists {
+		responseChannel <- response
+	}
+}
+
+// reports a response which can not be decoded to the request of its ID, if
+// the ID can not be read either, the client fails all pending requests
+func (r *rpcClient) decodeFailed(message []byte, err error) {
+	var id interface{}
+	if r.cbor {
+		var envelope map[string]interface{}
+		if cborDecMode.Unmarshal(message, &envelope) == nil {
+			id = envelope["id"]
+		}
+	} else {
+		var envelope struct {
+			ID interface{} `json:"id"`
+		}
+		if json.Unmarshal(message, &envelope) == nil {
+			id = envelope.ID
+		}
+	}
+
+	if id == nil {
+		r.fail(fmt.Errorf("invalid response without an ID: %w", err))
+		return
+	}
+
+	r.deliver(rpcResponse{ID: id, Error: &rpcError{Message: fmt.Sprintf("invalid response: %v", err)}})
+}
+
+// marks the client as broken, all pending and future requests fail
+func (r *rpcClient) fail(err error) {
+	r.doneOnce.Do(func() {
+		r.doneError = err
+		close(r.done)
+		r.conn.Close()
+	})
+}
+
+func (r *rpcClient) broken() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *rpcClient) close() {
+	r.writeMutex.Lock()
+	r.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second),
+	)
+	r.writeMutex.Unlock()
+
+	r.fail(errClosed)
+}
+
+func (r *rpcClient) send(ctx context.Context, method string, params ...interface{}) (interface{}, error) {
+	if r.broken() {
+		return nil, fmt.Errorf("%w: %v", errConnection, r.doneError)
+	}
+
+	id := strconv.FormatUint(r.requestID.Add(1), 10)
+
+	responseChannel := make(chan rpcResponse, 1)
+	r.responsesMutex.Lock()
+	r.responses[id] = responseChannel
+	r.responsesMutex.Unlock()
+
+	defer func() {
+		r.responsesMutex.Lock()
+		delete(r.responses, id)
+		r.responsesMutex.Unlock()
+	}()
+
+	request := rpcRequest{
+		ID:     id,
+		Method: method,
+		Params: params,
+	}
+
+	messageType := websocket.TextMessage
+	var message []byte
+	var err error
+	if r.cbor {
+		messageType = websocket.BinaryMessage
+		message, err = cborMarshal(request)
+	} else {
+		message, err = json.Marshal(request)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.writeMutex.Lock()
+	err = r.conn.WriteMessage(messageType, message)
+	r.writeMutex.Unlock()
+	if err != nil {
+		r.fail(err)
+		return nil, fmt.Errorf("%w: %v", errConnection, err)
+	}
+
+	timeout := time.NewTimer(r.timeout)
+	defer timeout.Stop()
+
+	select {
+	case response := <-responseChannel:
+		if method == "query" {
+			recordResponseSize(ctx, response.size)
+		}
+		if response.Error != nil {
+			return nil, response.Error
+		}
+		return response.Result, nil
+	case <-r.done:
+		return nil, fmt.Errorf("%w: %v", errConnection, r.doneError)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timeout.C:
+		return nil, fmt.Errorf("request '%s' timed out after %s", method, r.timeout)
+	}
+}
+
+func (r *rpcClient) signin(ctx context.Context, parameters map[string]interface{}) (interface{}, error) {
+	return r.send(ctx, "signin", parameters)
+}
+
+func (r *rpcClient) authenticate(ctx context.Context, token string) (interface{}, error) {
+	return r.send(ctx, "authenticate", token)
+}
+
+func (r *rpcClient) use(ctx context.Context, namespace string, database string) (interface{}, error) {
+	return r.send(ctx, "use", namespace, database)
+}
+
+func (r *rpcClient) query(ctx context.Context, query string, vars map[string]interface{}) (interface{}, error) {
+	return r.send(ctx, "query", query, vars)
+}
@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// answers every request with its first parameter as result
+func echoResponse(request rpcRequest) []byte {
+	var result interface{}
+	if len(request.Params) > 0 {
+		result = request.Params[0]
+	}
+	response, _ := json.Marshal(rpcResponse{ID: request.ID, Result: result})
+	return response
+}
+
+// starts a websocket server, which passes every connection to the handler
+func newRPCServer(t *testing.T, handler func(conn *websocket.Conn)) string {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/rpc"
+}
+
+func TestRPCResponseOrder(t *testing.T) {
+	// the responses are sent in the reverse order of the requests
+	location := newRPCServer(t, func(conn *websocket.Conn) {
+		requests := make([]rpcRequest, 2)
+		for index := range requests {
+			if conn.ReadJSON(&requests[index]) != nil {
+				return
+			}
+		}
+		for index := len(requests) - 1; index >= 0; index-- {
+			conn.WriteMessage(websocket.TextMessage, echoResponse(requests[index]))
+		}
+	})
+	ctx := context.Background()
+
+	client, err := newRPCClient(ctx, location, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.close()
+	client.timeout = time.Second
+
+	results := make([]interface{}, 2)
+	errs := make([]error, 2)
+	var wait sync.WaitGroup
+	for index := range results {
+		wait.Add(1)
+		go func(index int) {
+			defer wait.Done()
+			results[index], errs[index] = client.send(ctx, "echo", fmt.Sprintf("request %d", index))
+		}(index)
+	}
+	wait.Wait()
+
+	for index := range results {
+		expected := fmt.Sprintf("request %d", index)
+		if errs[index] != nil || results[index] != expected {
+			t.Errorf("expected '%s', got '%v' with error '%v'", expected, results[index], errs[index])
+		}
+	}
+}
+
+func TestRPCTimeout(t *testing.T) {
+	// the 'slow' request is answered after the next request arrived
+	location := newRPCServer(t, func(conn *websocket.Conn) {
+		var delayed []rpcRequest
+		for {
+			var request rpcRequest
+			if conn.ReadJSON(&request) != nil {
+				return
+			}
+			if request.Method == "slow" {
+				delayed = append(delayed, request)
+				continue
+			}
+			for _, late := range delayed {
+				conn.WriteMessage(websocket.TextMessage, echoResponse(late))
+			}
+			delayed = nil
+			conn.WriteMessage(websocket.TextMessage, echoResponse(request))
+		}
+	})
+	ctx := context.Background()
+
+	client, err := newRPCClient(ctx, location, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.close()
+	client.timeout = 50 * time.Millisecond
+
+	_, err = client.send(ctx, "slow", "slow")
+	if err == nil || strings.Contains(err.Error(), "timed out") == false {
+		t.Errorf("expected a timeout, got '%v'", err)
+	}
+	if client.broken() {
+		t.Errorf("expected the client to survive a timeout")
+	}
+
+	// the late response of the timed out request is dropped
+	result, err := client.send(ctx, "echo", "next")
+	if err != nil || result != "next" {
+		t.Errorf("expected 'next', got '%v' with error '%v'", result, err)
+	}
+
+	client.responsesMutex.Lock()
+	pending := len(client.responses)
+	client.responsesMutex.Unlock()
+	if pending != 0 {
+		t.Errorf("expected no pending requests, got %d", pending)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = client.send(cancelled, "slow", "cancelled")
+	if err != context.Canceled {
+		t.Errorf("expected the cancelled context, got '%v'", err)
+	}
+}
+
+func TestRPCConcurrent(t *testing.T) {
+	server := newFakeSurrealDB(t, 0, echoResponse)
+	ctx := context.Background()
+
+	client, err := newRPCClient(ctx, server.location(), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.close()
+	client.timeout = time.Second
+
+	const calls = 50
+	var wait sync.WaitGroup
+	for index := 0; index < calls; index++ {
+		wait.Add(1)
+		go func(index int) {
+			defer wait.Done()
+			expected := fmt.Sprintf("call %d", index)
+			result, err := client.send(ctx, "echo", expected)
+			if err != nil || result != expected {
+				t.Errorf("expected '%s', got '%v' with error '%v'", expected, result, err)
+			}
+		}(index)
+	}
+	wait.Wait()
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	ids := map[string]bool{}
+	for _, request := range server.requests {
+		if ids[request.ID] {
+			t.Errorf("expected unique request IDs, got '%s' twice", request.ID)
+		}
+		ids[request.ID] = true
+	}
+	if len(ids) != calls {
+		t.Errorf("expected %d requests, got %d", calls, len(ids))
+	}
+}
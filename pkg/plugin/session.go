@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// sessions which have not been used for this duration are closed
+const sessionIdleTimeout = 10 * time.Minute
+
+type session struct {
+	db       transport
+	lastUsed time.Time
+}
+
+type sessions struct {
+	mutex   sync.Mutex
+	entries map[string]*session
+}
+
+// returns the connection used for the queries of the given request, which
+// is the shared datasource connection unless the identity is forwarded
+func (r *Datasource) session(ctx context.Context, pCtx backend.PluginContext, headers backend.ForwardHTTPHeaders) (transport, error) {
+	if r.config.ForwardIdentity == "" {
+		return r.db, nil
+	}
+
+	config, key, err := r.sessionConfig(pCtx, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.sessionTransport(ctx, key, config)
+}
+
+// returns the configuration and the session key of the request identity
+func (r *Datasource) sessionConfig(pCtx backend.PluginContext, headers backend.ForwardHTTPHeaders) (configuration, string, error) {
+	config := r.config
+	if r.config.ForwardIdentity == "" {
+		return config, "datasource", nil
+	}
+
+	config.AuthMethod = r.config.ForwardIdentity
+	config.ScopeParameters = map[string]interface{}{}
+	for key, value := range r.config.ScopeParameters {
+		config.ScopeParameters[key] = value
+	}
+
+	var key string
+	switch r.config.ForwardIdentity {
+	case _SCOPE:
+		user := pCtx.User
+		if user == nil || user.Login == "" {
+			return config, "", fmt.Errorf("identity forwarding requires a signed in Grafana user")
+		}
+
+		config.ScopeParameters["login"] = user.Login
+		config.ScopeParameters["email"] = user.Email
+		config.ScopeParameters["name"] = user.Name
+		config.ScopeParameters["role"] = user.Role
+
+		// every forwarded parameter is part of the key, so that a changed
+		// role or email signs in a new session
+		parameters, err := json.Marshal(map[string]string{
+			"login": user.Login,
+			"email": user.Email,
+			"name":  user.Name,
+			"role":  user.Role,
+		})
+		if err != nil {
+			return config, "", err
+		}
+		digest := sha256.Sum256(parameters)
+		key = "scope:" + hex.EncodeToString(digest[:])
+	case _TOKEN:
+		token := headers.GetHTTPHeader(backend.OAuthIdentityIDTokenHeaderName)
+		if token == "" {
+			authorization := headers.GetHTTPHeader(backend.OAuthIdentityTokenHeaderName)
+			token, _ = strings.CutPrefix(authorization, "Bearer ")
+		}
+		if token == "" {
+			return config, "", fmt.Errorf("identity forwarding requires a forwarded OAuth token, enable 'oauthPassThru'")
+		}
+
+		config.Token = token
+
+		digest := sha256.Sum256([]byte(token))
+		key = "token:" + hex.EncodeToString(digest[:])
+	default:
+		return config, "", fmt.Errorf("unsupported identity forwarding '%s'", r.config.ForwardIdentity)
+	}
+
+	return config, key, nil
+}
+
+// returns the cached session transport of the key or creates a new one, the
+// transport is created without the lock, so that a slow signin does not block
+// the sessions of other users, a concurrently created duplicate is closed
+func (r *Datasource) sessionTransport(ctx context.Context, key string, config configuration) (transport, error) {
+	now := time.Now()
+
+	r.sessions.mutex.Lock()
+	expired := r.expireSessions(ctx, now)
+	entry, exists := r.sessions.entries[key]
+	if exists {
+		entry.lastUsed = now
+	}
+	r.sessions.mutex.Unlock()
+
+	closeTransports(expired)
+	if exists {
+		return entry.db, nil
+	}
+
+	// the connections of a session are only kept while they are in use
+	options := config.Pool
+	options.minSize = 0
+
+	db, err := newTransport(ctx, config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	r.sessions.mutex.Lock()
+	entry, exists = r.sessions.entries[key]
+	if exists {
+		entry.lastUsed = now
+	} else {
+		if r.sessions.entries == nil {
+			r.sessions.entries = map[string]*session{}
+		}
+		r.sessions.entries[key] = &session{
+			db:       db,
+			lastUsed: now,
+		}
+	}
+	r.sessions.mutex.Unlock()
+
+	if exists {
+		db.close()
+		return entry.db, nil
+	}
+
+	return db, nil
+}
+
+// removes idle sessions and returns their transports, which the caller closes
+// after releasing the sessions mutex, the caller must hold the mutex
+func (r *Datasource) expireSessions(ctx context.Context, now time.Time) []transport {
+	expired := []transport{}
+	for key, entry := range r.sessions.entries {
+		if now.Sub(entry.lastUsed) > sessionIdleTimeout {
+			expired = append(expired, entry.db)
+			delete(r.sessions.entries, key)
+			logger(ctx).Debug("Session expired", "identity", r.config.ForwardIdentity)
+		}
+	}
+	return expired
+}
+
+func closeTransports(transports []transport) {
+	for _, db := range transports {
+		db.close()
+	}
+}
+
+func (r *Datasource) closeSessions() {
+	r.sessions.mutex.Lock()
+	closed := []transport{}
+	for key, entry := range r.sessions.entries {
+		closed = append(closed, entry.db)
+		delete(r.sessions.entries, key)
+	}
+	r.sessions.mutex.Unlock()
+
+	closeTransports(closed)
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+type sessionTestTransport struct {
+	closed bool
+}
+
+func (t *sessionTestTransport) query(ctx context.Context, query string) (interface{}, error) {
+	return nil, nil
+}
+
+func (t *sessionTestTransport) close() {
+	t.closed = true
+}
+
+func TestSessionConfig(t *testing.T) {
+	user := &backend.User{Login: "viewer", Email: "viewer@example.com", Name: "Viewer", Role: "Viewer"}
+	idToken := backend.ForwardHTTPHeaders(&backend.QueryDataRequest{Headers: map[string]string{
+		backend.OAuthIdentityIDTokenHeaderName: "id-token",
+		backend.OAuthIdentityTokenHeaderName:   "Bearer access-token",
+	}})
+	accessToken := backend.ForwardHTTPHeaders(&backend.QueryDataRequest{Headers: map[string]string{
+		backend.OAuthIdentityTokenHeaderName: "Bearer access-token",
+	}})
+	noToken := backend.ForwardHTTPHeaders(&backend.QueryDataRequest{Headers: map[string]string{}})
+
+	tests := []struct {
+		forward string
+		user    *backend.User
+		headers backend.ForwardHTTPHeaders
+		token   string
+		key     string
+		invalid bool
+	}{
+		{"", user, noToken, "", "datasource", false},
+		{_SCOPE, user, noToken, "", "scope:", false},
+		{_SCOPE, nil, noToken, "", "", true},
+		{_SCOPE, &backend.User{}, noToken, "", "", true},
+		{_TOKEN, user, idToken, "id-token", "token:", false},
+		{_TOKEN, user, accessToken, "access-token", "token:", false},
+		{_TOKEN, user, noToken, "", "", true},
+		{"oauth", user, idToken, "", "", true},
+	}
+
+	for _, test := range tests {
+		ds := &Datasource{config: configuration{
+			ForwardIdentity: test.forward,
+			ScopeParameters: map[string]interface{}{"tenant": "acme"},
+		}}
+		config, key, err := ds.sessionConfig(backend.PluginContext{User: test.user}, test.headers)
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected result '%v'", test.forward, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if strings.HasPrefix(key, test.key) == false {
+			t.Errorf("%s: expected the key prefix '%s', got '%s'", test.forward, test.key, key)
+		}
+		if config.Token != test.token {
+			t.Errorf("%s: expected the token '%s', got '%s'", test.forward, test.token, config.Token)
+		}
+		if test.forward == _SCOPE {
+			if config.AuthMethod != _SCOPE || config.ScopeParameters["login"] != "viewer" || config.ScopeParameters["role"] != "Viewer" || config.ScopeParameters["tenant"] != "acme" {
+				t.Errorf("unexpected scope configuration '%v'", config)
+			}
+			if len(ds.config.ScopeParameters) != 1 {
+				t.Errorf("expected the datasource scope parameters to be unchanged")
+			}
+		}
+	}
+}
+
+func TestSessionConfigKey(t *testing.T) {
+	ds := &Datasource{config: configuration{ForwardIdentity: _SCOPE}}
+	headers := backend.ForwardHTTPHeaders(&backend.QueryDataRequest{})
+
+	_, viewer, err := ds.sessionConfig(backend.PluginContext{User: &backend.User{Login: "user", Role: "Viewer"}}, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, editor, err := ds.sessionConfig(backend.PluginContext{User: &backend.User{Login: "user", Role: "Editor"}}, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, email, err := ds.sessionConfig(backend.PluginContext{User: &backend.User{Login: "user", Role: "Viewer", Email: "user@example.com"}}, headers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if viewer == editor || viewer == email {
+		t.Errorf("expected a new session key for changed user parameters")
+	}
+}
+
+func TestExpireSessions(t *testing.T) {
+	now := time.Now()
+	idle := &sessionTestTransport{}
+	active := &sessionTestTransport{}
+
+	ds := &Datasource{}
+	ds.sessions.entries = map[string]*session{
+		"idle":   {db: idle, lastUsed: now.Add(-sessionIdleTimeout - time.Second)},
+		"active": {db: active, lastUsed: now.Add(-time.Minute)},
+	}
+
+	expired := ds.expireSessions(context.Background(), now)
+	if len(expired) != 1 || expired[0] != idle {
+		t.Fatalf("expected the idle session to expire, got %v", expired)
+	}
+	if _, exists := ds.sessions.entries["active"]; exists == false || len(ds.sessions.entries) != 1 {
+		t.Errorf("expected the active session to be kept, got %v", ds.sessions.entries)
+	}
+
+	// the caller closes the expired transports without the lock
+	closeTransports(expired)
+	if idle.closed == false || active.closed {
+		t.Errorf("expected only the idle transport to be closed")
+	}
+
+	// a cached session is reused and its idle time is reset
+	ds.sessions.entries["active"].lastUsed = now.Add(-time.Minute)
+	db, err := ds.sessionTransport(context.Background(), "active", configuration{})
+	if err != nil || db != active {
+		t.Fatalf("expected the cached session, got %v (%v)", db, err)
+	}
+	if time.Since(ds.sessions.entries["active"].lastUsed) > time.Second {
+		t.Errorf("expected the last use to be updated")
+	}
+
+	ds.closeSessions()
+	if active.closed == false || len(ds.sessions.entries) != 0 {
+		t.Errorf("expected all sessions to be closed")
+	}
+}
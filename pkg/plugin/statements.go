@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"strings"
+	"unicode"
+)
+
+// keywords of statements which change data or the schema
+var writeKeywords = map[string]bool{
+	"ALTER":   true,
+	"CREATE":  true,
+	"DEFINE":  true,
+	"DELETE":  true,
+	"INSERT":  true,
+	"KILL":    true,
+	"LIVE":    true,
+	"REBUILD": true,
+	"RELATE":  true,
+	"REMOVE":  true,
+	"UPDATE":  true,
+	"UPSERT":  true,
+}
+
+// a statement of a query without comments and its upper-case words, which
+// are outside of strings, quoted identifiers and record ids
+type statement struct {
+	text  string
+	words []string
+}
+
+// splits SurrealQL at the semicolons which end a statement, semicolons in
+// strings, identifiers, comments or blocks like '{ LET $a = 1; $a }' are kept
+func splitStatements(surql string) []statement {
+	statements := []statement{}
+
+	var text strings.Builder
+	var word strings.Builder
+	words := []string{}
+	depth := 0
+
+	endWord := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToUpper(word.String()))
+			word.Reset()
+		}
+	}
+	endStatement := func() {
+		endWord()
+		value := strings.TrimSpace(text.String())
+		if value != "" {
+			statements = append(statements, statement{text: value, words: words})
+		}
+		text.Reset()
+		words = []string{}
+	}
+
+	runes := []rune(surql)
+	for index := 0; index < len(runes); index++ {
+		current := runes[index]
+		next := rune(0)
+		if index+1 < len(runes) {
+			next = runes[index+1]
+		}
+
+		switch {
+		case current == '\'' || current == '"' || current == '`' || current == '⟨':
+			endWord()
+			closing := current
+			if current == '⟨' {
+				closing = '⟩'
+			}
+			text.WriteRune(current)
+			for index++; index < len(runes); index++ {
+				text.WriteRune(runes[index])
+				if runes[index] == '\\' && index+1 < len(runes) {
+					index++
+					text.WriteRune(runes[index])
+					continue
+				}
+				if runes[index] == closing {
+					break
+				}
+			}
+		case current == '#' || (current == '-' && next == '-') || (current == '/' && next == '/'):
+			endWord()
+			for index < len(runes) && runes[index] != '\n' {
+				index++
+			}
+			text.WriteRune(' ')
+		case current == '/' && next == '*':
+			endWord()
+			for index += 2; index < len(runes); index++ {
+				if runes[index] == '*' && index+1 < len(runes) && runes[index+1] == '/' {
+					index++
+					break
+				}
+			}
+			text.WriteRune(' ')
+		case current == ';' && depth == 0:
+			endStatement()
+		case unicode.IsLetter(current) || unicode.IsDigit(current) || current == '_':
+			word.WriteRune(current)
+			text.WriteRune(current)
+		default:
+			endWord()
+			switch current {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				if depth > 0 {
+					depth--
+				}
+			}
+			text.WriteRune(current)
+		}
+	}
+	endStatement()
+
+	return statements
+}
+
+// returns the first keyword of the statement
+func (s statement) keyword() string {
+	if len(s.words) == 0 {
+		return ""
+	}
+	return s.words[0]
+}
+
+// reports if the statement, or any subquery of it, changes data or the schema
+func (s statement) writes() bool {
+	for _, word := range s.words {
+		if writeKeywords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+func joinStatements(statements []statement) string {
+	texts := []string{}
+	for _, entry := range statements {
+		texts = append(texts, entry.text)
+	}
+	return strings.Join(texts, "; ")
+}
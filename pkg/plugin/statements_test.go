@@ -0,0 +1,46 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		surql    string
+		expected []string
+		keywords []string
+		writes   []bool
+	}{
+		{"", []string{}, []string{}, []bool{}},
+		{"select * from a; ; select * from b;", []string{"select * from a", "select * from b"}, []string{"SELECT", "SELECT"}, []bool{false, false}},
+		{"select * from log where msg = 'a;b' or msg = \"c;d\"", []string{"select * from log where msg = 'a;b' or msg = \"c;d\""}, []string{"SELECT"}, []bool{false}},
+		{"select * from ⟨a;b⟩, `c;d`", []string{"select * from ⟨a;b⟩, `c;d`"}, []string{"SELECT"}, []bool{false}},
+		{"select * from log where msg = 'it\\'s; ok'", []string{"select * from log where msg = 'it\\'s; ok'"}, []string{"SELECT"}, []bool{false}},
+		{"return { let $a = 1; $a }; delete person", []string{"return { let $a = 1; $a }", "delete person"}, []string{"RETURN", "DELETE"}, []bool{false, true}},
+		{"select * from a -- x; delete b\n; select 1 /* ; */ from c # ;", []string{"select * from a", "select 1   from c"}, []string{"SELECT", "SELECT"}, []bool{false, false}},
+		{"return (create person)", []string{"return (create person)"}, []string{"RETURN"}, []bool{true}},
+		{"select * from log where msg = 'delete'", []string{"select * from log where msg = 'delete'"}, []string{"SELECT"}, []bool{false}},
+	}
+
+	for _, test := range tests {
+		statements := splitStatements(test.surql)
+		texts := []string{}
+		keywords := []string{}
+		writes := []bool{}
+		for _, entry := range statements {
+			texts = append(texts, entry.text)
+			keywords = append(keywords, entry.keyword())
+			writes = append(writes, entry.writes())
+		}
+		if reflect.DeepEqual(texts, test.expected) == false {
+			t.Errorf("%s: expected %q, got %q", test.surql, test.expected, texts)
+		}
+		if reflect.DeepEqual(keywords, test.keywords) == false {
+			t.Errorf("%s: expected keywords %v, got %v", test.surql, test.keywords, keywords)
+		}
+		if reflect.DeepEqual(writes, test.writes) == false {
+			t.Errorf("%s: expected writes %v, got %v", test.surql, test.writes, writes)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// the namespace and database of a query, which override the configured ones
+type queryTargetData struct {
+	Hide      bool   `json:"hide"`
+	Namespace string `json:"namespace"`
+	Database  string `json:"database"`
+}
+
+// returns the connection for the namespace and database of the query, an
+// override runs on an isolated session, which signs in and uses the
+// namespace and database like 'USE NS ... DB ...', so that the shared
+// connections keep the configured namespace and database
+func (r *Datasource) target(ctx context.Context, pCtx backend.PluginContext, headers backend.ForwardHTTPHeaders, db transport, dataQuery backend.DataQuery) (transport, error) {
+	var target queryTargetData
+	err := json.Unmarshal(dataQuery.JSON, &target)
+	if err != nil {
+		// reported by 'Datasource.queryData()'
+		return db, nil
+	}
+	if target.Hide {
+		return db, nil
+	}
+
+	if target.Namespace == "" {
+		target.Namespace = r.config.Namespace
+	}
+	if target.Database == "" {
+		target.Database = r.config.Database
+	}
+	if target.Namespace == r.config.Namespace && target.Database == r.config.Database {
+		return db, nil
+	}
+
+	err = allowTarget(r.config, target)
+	if err != nil {
+		return nil, err
+	}
+
+	config, key, err := r.sessionConfig(pCtx, headers)
+	if err != nil {
+		return nil, err
+	}
+	err = allowTargetAuth(config, target)
+	if err != nil {
+		return nil, err
+	}
+	config.Namespace = target.Namespace
+	config.Database = target.Database
+
+	// names can not contain the separator, see 'allowTarget()'
+	key = fmt.Sprintf("%s/%s/%s", key, target.Namespace, target.Database)
+
+	return r.sessionTransport(ctx, key, config)
+}
+
+// checks the namespace and database against the configured allowlists, which
+// contain names or patterns like 'tenant_*', without allowlists only the
+// configured namespace and database can be used
+func allowTarget(config configuration, target queryTargetData) error {
+	if allowName(config.Namespace, config.AllowedNamespaces, target.Namespace) == false {
+		return fmt.Errorf("namespace '%s' is not allowed", target.Namespace)
+	}
+	if allowName(config.Database, config.AllowedDatabases, target.Database) == false {
+		return fmt.Errorf("database '%s' is not allowed", target.Database)
+	}
+	return nil
+}
+
+// checks that the signin of the session is not bound to the namespace or
+// database, database and scope users can only use the database they signed
+// in to and namespace users only their namespace
+func allowTargetAuth(config configuration, target queryTargetData) error {
+	method := authMethod(config)
+	switch method {
+	case _DATABASE, _SCOPE:
+		return fmt.Errorf("the %s signin is bound to namespace '%s' and database '%s', overriding them requires a root or namespace user or a token", method, config.Namespace, config.Database)
+	case _NAMESPACE:
+		if target.Namespace != config.Namespace {
+			return fmt.Errorf("the %s signin is bound to namespace '%s', overriding it requires a root user or a token", method, config.Namespace)
+		}
+	}
+	return nil
+}
+
+func allowName(configured string, allowlist []string, name string) bool {
+	if name == configured {
+		return true
+	}
+	if name == "" || path.Base(name) != name {
+		return false
+	}
+
+	for _, pattern := range allowlist {
+		matched, err := path.Match(pattern, name)
+		if err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestAllowTarget(t *testing.T) {
+	config := configuration{
+		Namespace:         "main",
+		Database:          "main",
+		AllowedNamespaces: []string{"main", "shared"},
+		AllowedDatabases:  []string{"tenant_*"},
+	}
+
+	tests := []struct {
+		target  queryTargetData
+		invalid bool
+	}{
+		{queryTargetData{Namespace: "main", Database: "main"}, false},
+		{queryTargetData{Namespace: "main", Database: "tenant_acme"}, false},
+		{queryTargetData{Namespace: "shared", Database: "tenant_acme"}, false},
+		{queryTargetData{Namespace: "other", Database: "tenant_acme"}, true},
+		{queryTargetData{Namespace: "main", Database: "acme"}, true},
+		{queryTargetData{Namespace: "main", Database: "tenant_a/b"}, true},
+		{queryTargetData{Namespace: "main", Database: ""}, true},
+	}
+
+	for _, test := range tests {
+		err := allowTarget(config, test.target)
+		if (err != nil) != test.invalid {
+			t.Errorf("%+v: unexpected result '%v'", test.target, err)
+		}
+	}
+
+	err := allowTarget(configuration{Namespace: "main", Database: "main"}, queryTargetData{Namespace: "main", Database: "tenant_acme"})
+	if err == nil {
+		t.Errorf("expected an error without allowlists")
+	}
+}
+
+func TestAllowTargetAuth(t *testing.T) {
+	tests := []struct {
+		method  string
+		target  queryTargetData
+		invalid bool
+	}{
+		{_ROOT, queryTargetData{Namespace: "shared", Database: "tenant_acme"}, false},
+		{_TOKEN, queryTargetData{Namespace: "shared", Database: "tenant_acme"}, false},
+		{_NAMESPACE, queryTargetData{Namespace: "main", Database: "tenant_acme"}, false},
+		{_NAMESPACE, queryTargetData{Namespace: "shared", Database: "tenant_acme"}, true},
+		{_DATABASE, queryTargetData{Namespace: "main", Database: "tenant_acme"}, true},
+		{_SCOPE, queryTargetData{Namespace: "main", Database: "tenant_acme"}, true},
+		{"", queryTargetData{Namespace: "main", Database: "tenant_acme"}, true},
+	}
+
+	for _, test := range tests {
+		config := configuration{Namespace: "main", Database: "main", AuthMethod: test.method}
+		err := allowTargetAuth(config, test.target)
+		if (err != nil) != test.invalid {
+			t.Errorf("%s %+v: unexpected result '%v'", test.method, test.target, err)
+		}
+	}
+}
+
+func TestQueryTarget(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sqlResult("1ms", req.Header.Get("NS")+"/"+req.Header.Get("DB"))(w, req)
+	})
+	ds := testDatasource(t, handler, configuration{
+		Namespace:        "main",
+		Database:         "main",
+		AuthMethod:       _ROOT,
+		AllowedDatabases: []string{"tenant_*"},
+	})
+
+	ctx := context.Background()
+
+	tests := []struct {
+		json     string
+		expected string
+		invalid  bool
+	}{
+		{`{"surql":"return 1"}`, "main/main", false},
+		{`{"database":"tenant_acme"}`, "main/tenant_acme", false},
+		{`{"database":"tenant_acme","hide":true}`, "main/main", false},
+		{`{"database":"acme"}`, "", true},
+	}
+
+	for _, test := range tests {
+		queryDB, err := ds.target(ctx, backend.PluginContext{}, &backend.QueryDataRequest{}, ds.db, backend.DataQuery{JSON: []byte(test.json)})
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected result '%v'", test.json, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		response, err := ds.query(ctx, queryDB, "return session::ns()")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.result != test.expected {
+			t.Errorf("%s: expected '%s', got '%v'", test.json, test.expected, response.result)
+		}
+	}
+
+	if len(ds.sessions.entries) != 1 {
+		t.Errorf("expected one isolated session, got %d", len(ds.sessions.entries))
+	}
+}
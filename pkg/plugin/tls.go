@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// the HTTP transport also accepts the schemes 'http' and 'https'
+const (
+	_WS    = "ws"
+	_WSS   = "wss"
+	_HTTPS = "https"
+)
+
+// validates the scheme and TLS settings of the configuration in the same way
+// as the Grafana TLS settings 'tlsAuth', 'tlsAuthWithCACert' and 'tlsSkipVerify'
+func newTLSConfig(config configuration) (*tls.Config, error) {
+	schemes := []string{_WS, _WSS}
+	if config.Transport == _HTTP {
+		schemes = append(schemes, _HTTP, _HTTPS)
+	}
+	if contains(schemes, config.Scheme) == false {
+		return nil, fmt.Errorf("unsupported scheme '%s', expected '%s'", config.Scheme, strings.Join(schemes, "', '"))
+	}
+
+	if secureScheme(config.Scheme) == false {
+		if config.TLSAuth || config.TLSAuthWithCACert || config.TLSSkipVerify {
+			return nil, fmt.Errorf("TLS settings require the scheme '%s' or '%s', but '%s' is configured", _WSS, _HTTPS, config.Scheme)
+		}
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.TLSSkipVerify,
+		ServerName:         config.TLSServerName,
+	}
+
+	if config.TLSAuthWithCACert {
+		if strings.TrimSpace(config.TLSCACert) == "" {
+			return nil, fmt.Errorf("TLS CA certificate is enabled, but 'tlsCACert' is empty")
+		}
+
+		pool := x509.NewCertPool()
+		if pool.AppendCertsFromPEM([]byte(config.TLSCACert)) == false {
+			return nil, fmt.Errorf("TLS CA certificate 'tlsCACert' contains no valid PEM certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.TLSAuth {
+		if strings.TrimSpace(config.TLSClientCert) == "" || strings.TrimSpace(config.TLSClientKey) == "" {
+			return nil, fmt.Errorf("TLS client authentication is enabled, but 'tlsClientCert' or 'tlsClientKey' is empty")
+		}
+
+		certificate, err := tls.X509KeyPair([]byte(config.TLSClientCert), []byte(config.TLSClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("TLS client certificate or key is invalid: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// describes the configured transport security for the health check
+func describeTLS(config configuration) string {
+	transport := config.Transport
+	if transport == "" {
+		transport = _WEBSOCKET
+	}
+
+	if secureScheme(config.Scheme) == false {
+		return transport + " unencrypted"
+	}
+
+	features := []string{transport + " with TLS"}
+	if config.TLSAuthWithCACert {
+		features = append(features, "custom CA certificate")
+	}
+	if config.TLSAuth {
+		features = append(features, "client certificate")
+	}
+	if config.TLSSkipVerify {
+		features = append(features, "skipped certificate verification")
+	}
+
+	return strings.Join(features, ", ")
+}
+
+func secureScheme(scheme string) bool {
+	return scheme == _WSS || scheme == _HTTPS
+}
+
+// returns the scheme of the HTTP endpoints for the configured scheme
+func httpScheme(scheme string) string {
+	if secureScheme(scheme) {
+		return _HTTPS
+	}
+	return _HTTP
+}
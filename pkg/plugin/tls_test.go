@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"testing"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	tests := []struct {
+		config  configuration
+		invalid bool
+	}{
+		{configuration{Scheme: _WS}, false},
+		{configuration{Scheme: _WSS}, false},
+		{configuration{Scheme: _WSS, TLSSkipVerify: true}, false},
+		{configuration{Scheme: _HTTP}, true},
+		{configuration{Scheme: _HTTP, Transport: _HTTP}, false},
+		{configuration{Scheme: _HTTPS, Transport: _HTTP, TLSSkipVerify: true}, false},
+		{configuration{Scheme: _WSS, Transport: _HTTP}, false},
+		{configuration{Scheme: _HTTP, Transport: _HTTP, TLSSkipVerify: true}, true},
+		{configuration{Scheme: _WS, TLSSkipVerify: true}, true},
+		{configuration{Scheme: _WSS, TLSAuthWithCACert: true}, true},
+		{configuration{Scheme: _WSS, TLSAuthWithCACert: true, TLSCACert: "invalid"}, true},
+		{configuration{Scheme: _WSS, TLSAuth: true, TLSClientCert: "invalid"}, true},
+	}
+
+	for _, test := range tests {
+		tlsConfig, err := newTLSConfig(test.config)
+		if (err != nil) != test.invalid {
+			t.Errorf("%+v: unexpected result '%v'", test.config, err)
+		}
+
+		if err == nil && secureScheme(test.config.Scheme) && tlsConfig.InsecureSkipVerify != test.config.TLSSkipVerify {
+			t.Errorf("%+v: unexpected skip verify setting", test.config)
+		}
+	}
+}
+
+func TestHTTPScheme(t *testing.T) {
+	tests := map[string]string{
+		_WS:    _HTTP,
+		_WSS:   _HTTPS,
+		_HTTP:  _HTTP,
+		_HTTPS: _HTTPS,
+	}
+
+	for scheme, expected := range tests {
+		if result := httpScheme(scheme); result != expected {
+			t.Errorf("%s: expected '%s', got '%s'", scheme, expected, result)
+		}
+	}
+}
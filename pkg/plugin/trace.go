@@ -0,0 +1,274 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// a span of the result mapped by the configured columns
+type traceSpan struct {
+	traceID       string
+	spanID        string
+	parentSpanID  *string
+	serviceName   string
+	operationName string
+	startTime     time.Time
+	duration      float64
+	tags          json.RawMessage
+}
+
+// key value pair of the trace frame tags
+type traceTag struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// maps the span records with the configured columns, the start time is a
+// datetime or epoch milliseconds and the duration a SurrealDB duration or
+// milliseconds
+func traceSpans(request queryRequestData, result interface{}) ([]traceSpan, error) {
+	records, isArray := result.([]interface{})
+	if isArray == false {
+		return nil, fmt.Errorf("trace returned '%v' instead of an array", result)
+	}
+
+	spans := []traceSpan{}
+	for _, entry := range records {
+		record, isMap := entry.(map[string]interface{})
+		if isMap == false {
+			return nil, fmt.Errorf("trace returned the invalid span '%v'", entry)
+		}
+
+		traceID, isTraceID := traceString(record[request.TraceID])
+		spanID, isSpanID := traceString(record[request.TraceSpanID])
+		if isTraceID == false || isSpanID == false {
+			return nil, fmt.Errorf("span '%v' has no '%s' or '%s'", record, request.TraceID, request.TraceSpanID)
+		}
+
+		span := traceSpan{
+			traceID: traceID,
+			spanID:  spanID,
+		}
+		if parentSpanID, isParent := traceString(record[request.TraceParentSpanID]); isParent {
+			span.parentSpanID = &parentSpanID
+		}
+		span.serviceName, _ = traceString(record[request.TraceServiceName])
+		span.operationName, _ = traceString(record[request.TraceOperationName])
+
+		var err error
+		span.startTime, err = traceStartTime(record[request.TraceStartTime])
+		if err != nil {
+			return nil, fmt.Errorf("span '%s' has an invalid '%s': %w", spanID, request.TraceStartTime, err)
+		}
+		span.duration, err = traceDuration(record[request.TraceDuration])
+		if err != nil {
+			return nil, fmt.Errorf("span '%s' has an invalid '%s': %w", spanID, request.TraceDuration, err)
+		}
+		span.tags, err = traceTags(record[request.TraceTags])
+		if err != nil {
+			return nil, fmt.Errorf("span '%s' has invalid '%s': %w", spanID, request.TraceTags, err)
+		}
+
+		spans = append(spans, span)
+	}
+
+	return spans, nil
+}
+
+func traceString(value interface{}) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	result := fmt.Sprintf("%v", value)
+	return result, result != ""
+}
+
+func traceStartTime(value interface{}) (time.Time, error) {
+	switch startTime := value.(type) {
+	case time.Time:
+		return startTime, nil
+	case string:
+		return time.Parse(time.RFC3339Nano, startTime)
+	case float64:
+		return time.UnixMicro(int64(startTime * 1000)).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported start time '%v'", value)
+}
+
+// returns the duration in milliseconds
+func traceDuration(value interface{}) (float64, error) {
+	switch duration := value.(type) {
+	case time.Duration:
+		return float64(duration.Microseconds()) / 1000, nil
+	case string:
+		parsed, err := parseSurrealDuration(duration)
+		if err != nil {
+			return 0, err
+		}
+		return float64(parsed.Microseconds()) / 1000, nil
+	case float64:
+		return duration, nil
+	}
+	return 0, fmt.Errorf("unsupported duration '%v'", value)
+}
+
+// encodes an object as sorted key value pairs, arrays are expected to
+// contain key value pairs already
+func traceTags(value interface{}) (json.RawMessage, error) {
+	switch tags := value.(type) {
+	case nil:
+		return json.RawMessage("[]"), nil
+	case []interface{}:
+		return json.Marshal(tags)
+	case map[string]interface{}:
+		keys := []string{}
+		for key := range tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		pairs := []traceTag{}
+		for _, key := range keys {
+			pairs = append(pairs, traceTag{Key: key, Value: tags[key]})
+		}
+		return json.Marshal(pairs)
+	}
+	return nil, fmt.Errorf("unsupported tags '%v'", value)
+}
+
+// converts the span records into the trace frame of the trace view
+// https://grafana.com/docs/grafana/latest/panels-visualizations/visualizations/traces/#data-api
+func (r *Datasource) trace(query *queryData, frameMeta *data.FrameMeta, dataResponse *backend.DataResponse) error {
+	spans, err := traceSpans(query.request, query.response.result)
+	if err != nil {
+		return err
+	}
+
+	if query.request.TraceSearch {
+		return r.traceSearch(query, spans, frameMeta, dataResponse)
+	}
+
+	traceIDField := []string{}
+	spanIDField := []string{}
+	parentSpanIDField := []*string{}
+	serviceNameField := []string{}
+	operationNameField := []string{}
+	startTimeField := []float64{}
+	durationField := []float64{}
+	tagsField := []json.RawMessage{}
+
+	for _, span := range spans {
+		traceIDField = append(traceIDField, span.traceID)
+		spanIDField = append(spanIDField, span.spanID)
+		parentSpanIDField = append(parentSpanIDField, span.parentSpanID)
+		serviceNameField = append(serviceNameField, span.serviceName)
+		operationNameField = append(operationNameField, span.operationName)
+		startTimeField = append(startTimeField, float64(span.startTime.UnixMicro())/1000)
+		durationField = append(durationField, span.duration)
+		tagsField = append(tagsField, span.tags)
+	}
+
+	frame := data.NewFrame(
+		query.name,
+		data.NewField("traceID", nil, traceIDField),
+		data.NewField("spanID", nil, spanIDField),
+		data.NewField("parentSpanID", nil, parentSpanIDField),
+		data.NewField("serviceName", nil, serviceNameField),
+		data.NewField("operationName", nil, operationNameField),
+		data.NewField("startTime", nil, startTimeField),
+		data.NewField("duration", nil, durationField),
+		data.NewField("tags", nil, tagsField),
+	)
+
+	meta := *frameMeta
+	meta.PreferredVisualization = data.VisTypeTrace
+	frame.Meta = &meta
+
+	dataResponse.Frames = append(dataResponse.Frames, frame)
+
+	return nil
+}
+
+// summarizes the spans as a table with one row per trace, named by its root
+// span and ordered by the start time, the most recent trace first
+func (r *Datasource) traceSearch(query *queryData, spans []traceSpan, frameMeta *data.FrameMeta, dataResponse *backend.DataResponse) error {
+	type traceSummary struct {
+		root  traceSpan
+		start time.Time
+		end   time.Time
+		spans int64
+	}
+
+	traceIDs := []string{}
+	traces := map[string]*traceSummary{}
+	for _, span := range spans {
+		end := span.startTime.Add(time.Duration(span.duration * float64(time.Millisecond)))
+
+		summary, exists := traces[span.traceID]
+		if exists == false {
+			summary = &traceSummary{root: span, start: span.startTime, end: end}
+			traces[span.traceID] = summary
+			traceIDs = append(traceIDs, span.traceID)
+		}
+		summary.spans++
+
+		isRoot := span.parentSpanID == nil
+		wasRoot := summary.root.parentSpanID == nil
+		if (isRoot && wasRoot == false) || (isRoot == wasRoot && span.startTime.Before(summary.root.startTime)) {
+			summary.root = span
+		}
+		if span.startTime.Before(summary.start) {
+			summary.start = span.startTime
+		}
+		if end.After(summary.end) {
+			summary.end = end
+		}
+	}
+
+	sort.SliceStable(traceIDs, func(i, j int) bool {
+		return traces[traceIDs[i]].start.After(traces[traceIDs[j]].start)
+	})
+
+	traceIDField := []string{}
+	serviceNameField := []string{}
+	operationNameField := []string{}
+	startTimeField := []time.Time{}
+	durationField := []float64{}
+	spansField := []int64{}
+
+	for _, traceID := range traceIDs {
+		summary := traces[traceID]
+		traceIDField = append(traceIDField, traceID)
+		serviceNameField = append(serviceNameField, summary.root.serviceName)
+		operationNameField = append(operationNameField, summary.root.operationName)
+		startTimeField = append(startTimeField, summary.start)
+		durationField = append(durationField, float64(summary.end.Sub(summary.start).Microseconds())/1000)
+		spansField = append(spansField, summary.spans)
+	}
+
+	duration := data.NewField("duration", nil, durationField)
+	duration.Config = &data.FieldConfig{Unit: "ms"}
+
+	frame := data.NewFrame(
+		query.name,
+		data.NewField("traceID", nil, traceIDField),
+		data.NewField("serviceName", nil, serviceNameField),
+		data.NewField("operationName", nil, operationNameField),
+		data.NewField("startTime", nil, startTimeField),
+		duration,
+		data.NewField("spans", nil, spansField),
+	)
+
+	meta := *frameMeta
+	meta.PreferredVisualization = data.VisTypeTable
+	frame.Meta = &meta
+
+	dataResponse.Frames = append(dataResponse.Frames, frame)
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func traceTestRequest() queryRequestData {
+	return queryRequestData{
+		TraceID:            "trace",
+		TraceSpanID:        "span",
+		TraceParentSpanID:  "parent",
+		TraceServiceName:   "service",
+		TraceOperationName: "operation",
+		TraceStartTime:     "start",
+		TraceDuration:      "duration",
+		TraceTags:          "attributes",
+	}
+}
+
+func traceTestResult() []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"trace": "t1", "span": "a", "parent": nil, "service": "api", "operation": "GET /users",
+			"start": "2024-01-01T00:00:00Z", "duration": "25ms",
+			"attributes": map[string]interface{}{"http.status": float64(200), "http.method": "GET"},
+		},
+		map[string]interface{}{
+			"trace": "t1", "span": "b", "parent": "a", "service": "db", "operation": "select",
+			"start": "2024-01-01T00:00:00.005Z", "duration": float64(30),
+		},
+		map[string]interface{}{
+			"trace": "t2", "span": "c", "service": "api", "operation": "GET /health",
+			"start": time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC), "duration": 2 * time.Millisecond,
+		},
+	}
+}
+
+func TestTraceValues(t *testing.T) {
+	start, err := traceStartTime(float64(1704067200123.5))
+	if err != nil || start.UnixMicro() != 1704067200123500 {
+		t.Errorf("unexpected start time '%v' (%v)", start, err)
+	}
+	if _, err := traceStartTime(true); err == nil {
+		t.Errorf("expected an invalid start time")
+	}
+
+	durations := []struct {
+		value    interface{}
+		expected float64
+		invalid  bool
+	}{
+		{"1s500ms", 1500, false},
+		{"250µs", 0.25, false},
+		{float64(12.5), 12.5, false},
+		{3 * time.Millisecond, 3, false},
+		{"1x", 0, true},
+		{nil, 0, true},
+	}
+	for _, test := range durations {
+		duration, err := traceDuration(test.value)
+		if (err != nil) != test.invalid || duration != test.expected {
+			t.Errorf("%v: expected %v, got %v (%v)", test.value, test.expected, duration, err)
+		}
+	}
+
+	tags := []struct {
+		value    interface{}
+		expected string
+		invalid  bool
+	}{
+		{nil, `[]`, false},
+		{map[string]interface{}{"b": float64(1), "a": "x"}, `[{"key":"a","value":"x"},{"key":"b","value":1}]`, false},
+		{[]interface{}{map[string]interface{}{"key": "a", "value": "x"}}, `[{"key":"a","value":"x"}]`, false},
+		{"a=x", "", true},
+	}
+	for _, test := range tags {
+		result, err := traceTags(test.value)
+		if (err != nil) != test.invalid || string(result) != test.expected {
+			t.Errorf("%v: expected '%s', got '%s' (%v)", test.value, test.expected, string(result), err)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	query := queryData{
+		name:     "A",
+		request:  traceTestRequest(),
+		response: queryResponseData{result: traceTestResult()},
+	}
+
+	ds := Datasource{}
+	frameMeta := &data.FrameMeta{}
+
+	var dataResponse backend.DataResponse
+	err := ds.trace(&query, frameMeta, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := dataResponse.Frames[0]
+	if frame.Meta.PreferredVisualization != data.VisTypeTrace {
+		t.Errorf("expected visualization '%s', got '%s'", data.VisTypeTrace, frame.Meta.PreferredVisualization)
+	}
+	if frame.Rows() != 3 {
+		t.Fatalf("expected 3 spans, got %d", frame.Rows())
+	}
+
+	parent, _ := frame.FieldByName("parentSpanID")
+	if parent.At(0).(*string) != nil || *parent.At(1).(*string) != "a" {
+		t.Errorf("unexpected parent span ids '%v', '%v'", parent.At(0), parent.At(1))
+	}
+
+	startTime, _ := frame.FieldByName("startTime")
+	if value := startTime.At(1).(float64); value != 1704067200005 {
+		t.Errorf("expected start time 1704067200005, got %v", value)
+	}
+
+	duration, _ := frame.FieldByName("duration")
+	if value := duration.At(0).(float64); value != 25 {
+		t.Errorf("expected duration 25, got %v", value)
+	}
+
+	tags, _ := frame.FieldByName("tags")
+	if value := string(tags.At(0).(json.RawMessage)); value != `[{"key":"http.method","value":"GET"},{"key":"http.status","value":200}]` {
+		t.Errorf("unexpected tags '%s'", value)
+	}
+
+	query.response.result = []interface{}{map[string]interface{}{"trace": "t1"}}
+	if err := ds.trace(&query, frameMeta, &dataResponse); err == nil {
+		t.Errorf("expected an error for a span without id")
+	}
+}
+
+func TestTraceSearch(t *testing.T) {
+	request := traceTestRequest()
+	request.TraceSearch = true
+
+	query := queryData{
+		name:     "A",
+		request:  request,
+		response: queryResponseData{result: traceTestResult()},
+	}
+
+	ds := Datasource{}
+	frameMeta := &data.FrameMeta{}
+
+	var dataResponse backend.DataResponse
+	err := ds.trace(&query, frameMeta, &dataResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := dataResponse.Frames[0]
+	if frame.Meta.PreferredVisualization != data.VisTypeTable {
+		t.Errorf("expected visualization '%s', got '%s'", data.VisTypeTable, frame.Meta.PreferredVisualization)
+	}
+	if frame.Rows() != 2 {
+		t.Fatalf("expected 2 traces, got %d", frame.Rows())
+	}
+
+	// the most recent trace first
+	expected := []struct {
+		traceID   string
+		operation string
+		duration  float64
+		spans     int64
+	}{
+		{"t2", "GET /health", 2, 1},
+		{"t1", "GET /users", 35, 2},
+	}
+	for index, test := range expected {
+		if value := frame.Fields[0].At(index); value != test.traceID {
+			t.Errorf("%d: expected trace '%s', got '%v'", index, test.traceID, value)
+		}
+		if value := frame.Fields[2].At(index); value != test.operation {
+			t.Errorf("%d: expected operation '%s', got '%v'", index, test.operation, value)
+		}
+		if value := frame.Fields[4].At(index); value != test.duration {
+			t.Errorf("%d: expected duration %v, got %v", index, test.duration, value)
+		}
+		if value := frame.Fields[5].At(index); value != test.spans {
+			t.Errorf("%d: expected %d spans, got %v", index, test.spans, value)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// starts a span with the tracer of the SDK, which is configured by Grafana
+// https://pkg.go.dev/github.com/grafana/grafana-plugin-sdk-go/backend/tracing
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.DefaultTracer().Start(
+		ctx,
+		"surrealdb."+name,
+		trace.WithAttributes(attributes...),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// runs a stage of the query pipeline in its own span
+func traced(ctx context.Context, name string, stage func() error) error {
+	_, span := startSpan(ctx, name)
+	err := stage()
+	endSpan(span, err)
+	return err
+}
+
+// records the frames and the error of a query response
+func endQuerySpan(span trace.Span, response backend.DataResponse) {
+	rows := 0
+	for _, frame := range response.Frames {
+		rows = rows + frame.Rows()
+	}
+
+	span.SetAttributes(
+		attribute.Int("frames", len(response.Frames)),
+		attribute.Int("rows", rows),
+		attribute.String("status", fmt.Sprintf("%d", response.Status)),
+	)
+
+	endSpan(span, response.Error)
+}
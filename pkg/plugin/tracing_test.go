@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryDataSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := tracing.DefaultTracer()
+	tracing.InitDefaultTracer(provider.Tracer("test"))
+	defer tracing.InitDefaultTracer(previous)
+
+	ds := testDatasource(t, sqlResult("1.5ms", []interface{}{
+		map[string]interface{}{"timestamp": "2024-01-01T00:00:00Z", "value": 1},
+		map[string]interface{}{"timestamp": "2024-01-01T00:01:00Z", "value": 2},
+	}), configuration{})
+
+	ctx := context.Background()
+	_, err := ds.QueryData(ctx, &backend.QueryDataRequest{
+		Queries: []backend.DataQuery{
+			{RefID: "A", JSON: []byte(`{"mode":"metric","surql":"select * from metric","transforms":[{"function":"cumulativeSum"}]}`)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+
+	for _, name := range []string{
+		"surrealdb.QueryData",
+		"surrealdb.queryData",
+		"surrealdb.macros",
+		"surrealdb.query",
+		"surrealdb.process",
+		"surrealdb.metric",
+		"surrealdb.metricTransform",
+	} {
+		if _, exists := spans[name]; exists == false {
+			t.Errorf("missing span '%s'", name)
+		}
+	}
+
+	attributes := func(name string) map[string]string {
+		result := map[string]string{}
+		for _, attribute := range spans[name].Attributes() {
+			result[string(attribute.Key)] = attribute.Value.Emit()
+		}
+		return result
+	}
+
+	queryData := attributes("surrealdb.queryData")
+	if queryData["refId"] != "A" || queryData["mode"] != "metric" || queryData["rows"] != "2" {
+		t.Errorf("unexpected queryData attributes '%v'", queryData)
+	}
+
+	query := attributes("surrealdb.query")
+	if query["surrealdb.status"] != "OK" || query["surrealdb.time"] != "1.5ms" {
+		t.Errorf("unexpected query attributes '%v'", query)
+	}
+
+	if spans["surrealdb.query"].Parent().SpanID() != spans["surrealdb.queryData"].SpanContext().SpanID() {
+		t.Errorf("expected the query span to be a child of the queryData span")
+	}
+}
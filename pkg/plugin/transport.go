@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	_WEBSOCKET = "websocket"
+	_HTTP      = "http"
+)
+
+// runs SurQL queries against SurrealDB and returns the list of statement
+// results, each with 'status', 'time' and 'result' (see 'Datasource.query()')
+type transport interface {
+	query(ctx context.Context, query string) (interface{}, error)
+	close()
+}
+
+var (
+	_ transport = (*pool)(nil)
+	_ transport = (*httpTransport)(nil)
+	_ transport = (*cluster)(nil)
+)
+
+// creates and authenticates the configured transport, which distributes the
+// queries over the endpoints if several are configured
+func newTransport(ctx context.Context, config configuration, options poolOptions) (transport, error) {
+	if len(config.Endpoints) > 1 {
+		return newCluster(ctx, config, options)
+	}
+	return newEndpointTransport(ctx, config, options)
+}
+
+// creates and authenticates the transport of a single endpoint
+func newEndpointTransport(ctx context.Context, config configuration, options poolOptions) (transport, error) {
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Transport {
+	case "", _WEBSOCKET:
+		location := fmt.Sprintf("%s://%s/rpc", config.Scheme, config.Location)
+		return newPool(ctx, location, tlsConfig, config, options)
+	case _HTTP:
+		location := fmt.Sprintf("%s://%s", httpScheme(config.Scheme), config.Location)
+		return newHTTPTransport(ctx, location, tlsConfig, config, options)
+	default:
+		return nil, fmt.Errorf("unsupported transport '%s', expected '%s' or '%s'", config.Transport, _WEBSOCKET, _HTTP)
+	}
+}
@@ -0,0 +1,213 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// runs queries through the SurrealDB HTTP endpoint '/sql', which responds
+// with the same result envelope as the websocket RPC 'query' method
+// https://docs.surrealdb.com/docs/integration/http
+type httpTransport struct {
+	location string
+	config   configuration
+	client   *http.Client
+
+	mutex sync.Mutex
+	token string
+}
+
+type httpError struct {
+	Code        int    `json:"code"`
+	Details     string `json:"details"`
+	Description string `json:"description"`
+	Information string `json:"information"`
+}
+
+func newHTTPTransport(ctx context.Context, location string, tlsConfig *tls.Config, config configuration, options poolOptions) (*httpTransport, error) {
+	roundTripper := http.DefaultTransport.(*http.Transport).Clone()
+	roundTripper.TLSClientConfig = tlsConfig
+	roundTripper.MaxConnsPerHost = options.maxSize
+	roundTripper.MaxIdleConnsPerHost = options.maxSize
+	roundTripper.IdleConnTimeout = options.idleTimeout
+
+	t := &httpTransport{
+		location: location,
+		config:   config,
+		client: &http.Client{
+			Transport: roundTripper,
+			Timeout:   defaultRPCTimeout,
+		},
+	}
+
+	// verifies the location and credentials like the websocket signin
+	_, err := t.query(ctx, "return true")
+	if err != nil {
+		t.close()
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *httpTransport) query(ctx context.Context, query string) (interface{}, error) {
+	result, status, err := t.sql(ctx, query)
+	if status == http.StatusUnauthorized && authMethod(t.config) == _SCOPE {
+		// the scope token expired, signin again
+		t.mutex.Lock()
+		t.token = ""
+		t.mutex.Unlock()
+
+		result, _, err = t.sql(ctx, query)
+	}
+	return result, err
+}
+
+func (t *httpTransport) sql(ctx context.Context, query string) (interface{}, int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.location+"/sql", strings.NewReader(query))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if t.config.Version >= 2 {
+		// https://surrealdb.com/docs/surrealdb/integration/http#sql
+		request.Header.Set("Accept", "application/cbor")
+		request.Header.Set("Surreal-NS", t.config.Namespace)
+		request.Header.Set("Surreal-DB", t.config.Database)
+	} else {
+		request.Header.Set("Accept", "application/json")
+		request.Header.Set("NS", t.config.Namespace)
+		request.Header.Set("DB", t.config.Database)
+	}
+
+	err = t.authorize(ctx, request)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result interface{}
+	status, size, err := t.send(request, &result)
+	if err == nil {
+		recordResponseSize(ctx, size)
+	}
+	return result, status, err
+}
+
+// https://docs.surrealdb.com/docs/integration/http#signin
+func (t *httpTransport) authorize(ctx context.Context, request *http.Request) error {
+	method := authMethod(t.config)
+	switch method {
+	case _ROOT, _NAMESPACE, _DATABASE:
+		request.SetBasicAuth(t.config.Username, t.config.Password)
+		return nil
+	case _TOKEN:
+		if t.config.Token == "" {
+			return fmt.Errorf("authentication method '%s' requires a token", method)
+		}
+		request.Header.Set("Authorization", "Bearer "+t.config.Token)
+		return nil
+	case _SCOPE:
+	default:
+		return fmt.Errorf("unsupported authentication method '%s'", method)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.token == "" {
+		if accessMethod(t.config) == "" {
+			return fmt.Errorf("authentication method '%s' requires a scope", method)
+		}
+
+		signinParameters := map[string]interface{}{
+			"ns":   t.config.Namespace,
+			"db":   t.config.Database,
+			"user": t.config.Username,
+			"pass": t.config.Password,
+		}
+		if t.config.Version >= 2 {
+			signinParameters["ac"] = accessMethod(t.config)
+		} else {
+			signinParameters["sc"] = t.config.Scope
+		}
+		for key, value := range t.config.ScopeParameters {
+			signinParameters[key] = value
+		}
+
+		body, err := json.Marshal(signinParameters)
+		if err != nil {
+			return err
+		}
+
+		signin, err := http.NewRequestWithContext(ctx, http.MethodPost, t.location+"/signin", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		signin.Header.Set("Accept", "application/json")
+		signin.Header.Set("Content-Type", "application/json")
+
+		var response struct {
+			Token string `json:"token"`
+		}
+		_, _, err = t.send(signin, &response)
+		if err != nil {
+			return fmt.Errorf("signin as %s user failed: %w", method, err)
+		}
+		if response.Token == "" {
+			return fmt.Errorf("signin as %s user failed: no token returned", method)
+		}
+
+		t.token = response.Token
+	}
+
+	request.Header.Set("Authorization", "Bearer "+t.token)
+	return nil
+}
+
+// returns the status and the length of the received body
+func (t *httpTransport) send(request *http.Request, result interface{}) (int, int, error) {
+	response, err := t.client.Do(request)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", errConnection, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, len(body), fmt.Errorf("%w: %v", errConnection, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		var failure httpError
+		if (json.Unmarshal(body, &failure) == nil || cborUnmarshal(body, &failure) == nil) && (failure.Information != "" || failure.Description != "") {
+			message := failure.Information
+			if message == "" {
+				message = failure.Description
+			}
+			return response.StatusCode, len(body), fmt.Errorf("%s (%s)", message, response.Status)
+		}
+		return response.StatusCode, len(body), fmt.Errorf("unexpected response '%s'", response.Status)
+	}
+
+	if strings.HasPrefix(response.Header.Get("Content-Type"), "application/cbor") {
+		err = cborUnmarshal(body, result)
+	} else {
+		err = json.Unmarshal(body, result)
+	}
+	if err != nil {
+		return response.StatusCode, len(body), fmt.Errorf("invalid response: %w", err)
+	}
+
+	return response.StatusCode, len(body), nil
+}
+
+func (t *httpTransport) close() {
+	t.client.CloseIdleConnections()
+}
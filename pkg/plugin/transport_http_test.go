@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/signin":
+			var parameters map[string]interface{}
+			json.NewDecoder(req.Body).Decode(&parameters)
+			if parameters["sc"] != "viewer" && parameters["ac"] != "viewer" || parameters["tenant"] != "acme" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": 200, "token": "scope-token"})
+		case "/sql":
+			user, pass, basic := req.BasicAuth()
+			authorized := basic && user == "root" && pass == "secret" ||
+				req.Header.Get("Authorization") == "Bearer scope-token"
+			if authorized == false {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(httpError{Code: 401, Information: "There was a problem with authentication"})
+				return
+			}
+			namespace := req.Header.Get("NS") + req.Header.Get("Surreal-NS")
+			database := req.Header.Get("DB") + req.Header.Get("Surreal-DB")
+			if namespace != "test" || database != "test" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode([]interface{}{
+				map[string]interface{}{"status": "OK", "time": "1ms", "result": []interface{}{}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	options := poolOptions{maxSize: 2, idleTimeout: time.Minute}
+
+	tests := []struct {
+		config  configuration
+		invalid bool
+	}{
+		{configuration{Namespace: "test", Database: "test", Username: "root", Password: "secret"}, false},
+		{configuration{Namespace: "test", Database: "test", Username: "root", Password: "wrong"}, true},
+		{configuration{Namespace: "test", Database: "test", Scope: "viewer", ScopeParameters: map[string]interface{}{"tenant": "acme"}}, false},
+		{configuration{Namespace: "test", Database: "test", Access: "viewer", Version: 2, ScopeParameters: map[string]interface{}{"tenant": "acme"}}, false},
+		{configuration{Namespace: "test", Database: "test", Access: "editor", Version: 2, ScopeParameters: map[string]interface{}{"tenant": "acme"}}, true},
+	}
+
+	for _, test := range tests {
+		db, err := newHTTPTransport(ctx, server.URL, nil, test.config, options)
+		if (err != nil) != test.invalid {
+			t.Errorf("%+v: unexpected result '%v'", test.config, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		response, err := (&Datasource{}).query(ctx, db, "select * from metric")
+		if err != nil {
+			t.Error(err)
+		}
+		if response.status != "OK" {
+			t.Errorf("unexpected status '%v'", response.status)
+		}
+		db.close()
+	}
+}
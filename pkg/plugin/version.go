@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	_AUTO = "auto"
+
+	versionDetectTimeout = 5 * time.Second
+	defaultVersion       = 1
+)
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// resolves the configured SurrealDB major version, by default the version is
+// detected with the HTTP endpoint '/version' and 1.x is assumed on failure
+func resolveVersion(ctx context.Context, jsonData datasourceOptions, config configuration) (int, string, error) {
+	switch jsonData.Version {
+	case "", _AUTO:
+	case "1", "2":
+		version, _ := strconv.Atoi(jsonData.Version)
+		return version, "", nil
+	default:
+		return 0, "", fmt.Errorf("unsupported SurrealDB version '%s', expected '%s', '1' or '2'", jsonData.Version, _AUTO)
+	}
+
+	// the first reachable endpoint reports the version
+	locations := configEndpoints(config)
+	failures := make([]error, 0, len(locations))
+	for _, location := range locations {
+		endpointConfig := config
+		endpointConfig.Location = location
+
+		version, release, err := detectVersion(ctx, endpointConfig)
+		if err == nil {
+			return version, release, nil
+		}
+		failures = append(failures, fmt.Errorf("'%s': %w", location, err))
+	}
+
+	// the 2.x features fail later, the version should be configured instead
+	logger(ctx).Warn("SurrealDB version detection failed, assuming 1.x", "datasourceUid", config.UID, "endpoints", locations, "error", errors.Join(failures...))
+	return defaultVersion, "", nil
+}
+
+// https://surrealdb.com/docs/surrealdb/integration/http#version
+func detectVersion(ctx context.Context, config configuration) (int, string, error) {
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return 0, "", err
+	}
+
+	roundTripper := http.DefaultTransport.(*http.Transport).Clone()
+	roundTripper.TLSClientConfig = tlsConfig
+	client := &http.Client{
+		Transport: roundTripper,
+		Timeout:   versionDetectTimeout,
+	}
+	defer client.CloseIdleConnections()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/version", httpScheme(config.Scheme), config.Location), nil)
+	if err != nil {
+		return 0, "", err
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0, "", fmt.Errorf("unexpected response '%s'", response.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1024))
+	if err != nil {
+		return 0, "", err
+	}
+
+	return parseVersion(string(body))
+}
+
+// parses version responses like 'surrealdb-2.0.4'
+func parseVersion(value string) (int, string, error) {
+	match := versionPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, "", fmt.Errorf("invalid version '%s'", value)
+	}
+
+	major, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid version '%s'", value)
+	}
+
+	return major, match[0], nil
+}
+
+// describes the SurrealDB version for the health check
+func describeVersion(config configuration) string {
+	if config.Release != "" {
+		return "SurrealDB " + config.Release
+	}
+	return fmt.Sprintf("SurrealDB %d.x", config.Version)
+}
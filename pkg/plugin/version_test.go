@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		major   int
+		release string
+		invalid bool
+	}{
+		{"surrealdb-2.0.4", 2, "2.0.4", false},
+		{"surrealdb-1.5.3+20240612.abcdef", 1, "1.5.3", false},
+		{"2.1.0\n", 2, "2.1.0", false},
+		{"surrealdb", 0, "", true},
+	}
+
+	for _, test := range tests {
+		major, release, err := parseVersion(test.value)
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected error '%v'", test.value, err)
+		}
+		if major != test.major || release != test.release {
+			t.Errorf("%s: expected '%d' and '%s', got '%d' and '%s'", test.value, test.major, test.release, major, release)
+		}
+	}
+}
+
+func TestResolveVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/version" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("surrealdb-2.0.4"))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	config := configuration{Scheme: _WS, Location: strings.TrimPrefix(server.URL, "http://")}
+
+	tests := []struct {
+		version string
+		major   int
+		invalid bool
+	}{
+		{"", 2, false},
+		{_AUTO, 2, false},
+		{"1", 1, false},
+		{"3", 0, true},
+	}
+
+	for _, test := range tests {
+		major, _, err := resolveVersion(ctx, datasourceOptions{Version: test.version}, config)
+		if (err != nil) != test.invalid {
+			t.Errorf("%s: unexpected error '%v'", test.version, err)
+		}
+		if major != test.major {
+			t.Errorf("%s: expected '%d', got '%d'", test.version, test.major, major)
+		}
+	}
+
+	server.Close()
+	major, _, err := resolveVersion(ctx, datasourceOptions{}, config)
+	if err != nil || major != defaultVersion {
+		t.Errorf("expected the fallback '%d', got '%d' and '%v'", defaultVersion, major, err)
+	}
+}
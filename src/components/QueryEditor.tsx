@@ -4,6 +4,7 @@ import React,
 
 import
 { InlineField
+, Input
 , Select
 , InlineSwitch
 , QueryField
@@ -23,6 +24,7 @@ import
 import
 { MyDataSourceOptions
   , MyQuery
+  , MyTransform
 } from '../types';
 
 type Props = QueryEditorProps<DataSource, MyQuery, MyDataSourceOptions>;
@@ -31,6 +33,8 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
     const
     { mode
     , surql
+    , namespace
+    , database
     , requery
     , timestamp
     , logMessage
@@ -41,8 +45,65 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
     , rateZero
     , rateInterval
     , rateFunctions
+    , geo
+    , frameFormat
+    , transforms
+    , topK
+    , bottomK
+    , groupRankBy
+    , groupOther
+    , groupOrder
+    , histogramBuckets
+    , histogramStart
+    , histogramWidth
+    , histogramFactor
+    , histogramCount
+    , histogramBounds
+    , histogramFormat
+    , downsample
+    , limit
+    , paginate
+    , cursor
+    , explainFull
+    , graphTitle
+    , graphMainStat
+    , graphSecondaryStat
+    , graphColor
+    , traceSearch
+    , traceId
+    , traceSpanId
+    , traceParentSpanId
+    , traceServiceName
+    , traceOperationName
+    , traceStartTime
+    , traceDuration
+    , traceTags
     } = query;
 
+    // empty inputs remove the option, so that the backend uses its default
+    const onNumberChange = (key: keyof MyQuery) => (event: ChangeEvent<HTMLInputElement>) => {
+	let value = event.target.value;
+	onChange({ ...query, [key]: value === "" ? undefined : Number(value) });
+	if( requery ) {
+	    onRunQuery();
+	}
+    };
+
+    const groupLimit = topK ? "top" : bottomK ? "bottom" : "";
+
+    const transformOf = (name: string): MyTransform | undefined =>
+	(transforms || []).find( (transform: MyTransform) => transform.function === name );
+
+    // the window and alpha apply to every transform of the function
+    const onTransformChange = (name: string, changes: Partial<MyTransform>) => {
+	onChange({ ...query, transforms: (transforms || []).map(
+	    (transform: MyTransform) => transform.function === name ? { ...transform, ...changes } : transform
+	) });
+	if( requery ) {
+	    onRunQuery();
+	}
+    };
+
     if( query.group === undefined ){
 	query.group = false
     }
@@ -85,6 +146,10 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
             [ { value: "raw", label: "Raw" }
             , { value: "log", label: "Logs" }
             , { value: "metric", label: "Metric" }
+            , { value: "histogram", label: "Histogram" }
+            , { value: "explain", label: "Explain" }
+            , { value: "graph", label: "Graph" }
+            , { value: "trace", label: "Trace" }
             ]
         }
         isSearchable={false}
@@ -115,6 +180,48 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
       />
       </div>
       </InlineField>
+      <InlineField
+        label="Namespace"
+        labelWidth={12}
+        tooltip="Namespace of the query, by default the configured one. Overrides must be allowed in the data source configuration."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"default"}
+        portalOrigin=""
+        query={namespace}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, namespace: value || undefined });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+      <InlineField
+        label="Database"
+        labelWidth={12}
+        tooltip="Database of the query, by default the configured one. Overrides must be allowed in the data source configuration."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"default"}
+        portalOrigin=""
+        query={database}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, database: value || undefined });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
       </HorizontalGroup>
       <VerticalGroup>
       <InlineField
@@ -140,7 +247,47 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
       </InlineField>
       </VerticalGroup>
       <HorizontalGroup>
-{ (mode === "log" || mode === "metric") &&
+{ (mode === "explain") &&
+      <InlineField
+        label="Full"
+        labelWidth={12}
+        tooltip="EXPLAIN FULL executes the last SELECT statement to count the fetched records, the records are not returned."
+      >
+      <InlineSwitch
+        value={explainFull}
+        disabled={false}
+        transparent={false}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let checked = event.target.checked;
+            onChange({ ...query, explainFull: checked });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+{ (mode === "raw") &&
+      <InlineField
+        label="Geo"
+        labelWidth={12}
+        tooltip="Split geometry points into latitude and longitude fields and keep other geometries as GeoJSON."
+      >
+      <InlineSwitch
+        value={geo}
+        disabled={false}
+        transparent={false}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let checked = event.target.checked;
+            onChange({ ...query, geo: checked });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+{ (mode === "log" || mode === "metric" || mode === "histogram") &&
       <InlineField
         label="Time"
         labelWidth={12}
@@ -186,7 +333,7 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
       </div>
       </InlineField>
 }
-{ (mode === "metric") &&
+{ (mode === "metric" || mode === "histogram") &&
       <InlineField
         label="Data"
         labelWidth={12}
@@ -209,66 +356,113 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
           </div>
         </InlineField>
 }
-      </HorizontalGroup>
-      <HorizontalGroup>
-{ (mode === "metric") &&
+{ (mode === "graph") &&
       <InlineField
-        label="Group By"
+        label="Title"
         labelWidth={12}
-        tooltip="Enable metric grouping."
+        tooltip="Node title field, the record id if not present."
       >
-      <InlineSwitch
-        value={group}
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"name"}
+        portalOrigin=""
+        query={graphTitle}
         disabled={false}
-        transparent={false}
-        onChange={(event: ChangeEvent<HTMLInputElement>) => {
-            let checked = event.target.checked;
-            onChange({ ...query, group: checked });
+        onChange={(value: string) => {
+            onChange({ ...query, graphTitle: value });
             if( requery ) {
                 onRunQuery();
             }
         }}
+        onBlur={() => {}}
       />
+      </div>
       </InlineField>
 }
-{ (mode === "metric") && group &&
+{ (mode === "graph") &&
       <InlineField
-        label="Field"
-        labelWidth={14}
-        tooltip="Metric group by field."
+        label="Main Stat"
+        labelWidth={12}
+        tooltip="Node and edge main stat field."
       >
-      <div style={{ minWidth: 245 }}>
+      <div style={{ minWidth: 150 }}>
       <QueryField
-        placeholder={"group"}
+        placeholder={"mainStat"}
         portalOrigin=""
-        query={groupBy}
+        query={graphMainStat}
         disabled={false}
         onChange={(value: string) => {
-            onChange({ ...query, groupBy: value });
+            onChange({ ...query, graphMainStat: value });
             if( requery ) {
                 onRunQuery();
             }
         }}
         onBlur={() => {}}
-          />
-          </div>
-        </InlineField>
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "graph") &&
+      <InlineField
+        label="Secondary Stat"
+        labelWidth={12}
+        tooltip="Node and edge secondary stat field."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"secondaryStat"}
+        portalOrigin=""
+        query={graphSecondaryStat}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, graphSecondaryStat: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "graph") &&
+      <InlineField
+        label="Color"
+        labelWidth={12}
+        tooltip="Node and edge color field, a color or a number mapped to the color scheme."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"color"}
+        portalOrigin=""
+        query={graphColor}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, graphColor: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
 }
       </HorizontalGroup>
       <HorizontalGroup>
-{ (mode === "metric") &&
+{ (mode === "trace") &&
       <InlineField
-        label="Rate"
+        label="Search"
         labelWidth={12}
-        tooltip="Enable rate computation."
+        tooltip="Summarize the spans as a table with one row per trace, instead of the spans of a single trace."
       >
       <InlineSwitch
-        value={rate}
+        value={traceSearch}
         disabled={false}
         transparent={false}
         onChange={(event: ChangeEvent<HTMLInputElement>) => {
             let checked = event.target.checked;
-            onChange({ ...query, rate: checked });
+            onChange({ ...query, traceSearch: checked });
             if( requery ) {
                 onRunQuery();
             }
@@ -276,42 +470,66 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
       />
       </InlineField>
 }
-{ (mode === "metric") && rate &&
+{ (mode === "trace") &&
       <InlineField
-        label="Zero Vector"
-        labelWidth={14}
-        tooltip="Convert the null vector to a zero vector."
+        label="Trace ID"
+        labelWidth={12}
+        tooltip="Trace id field of the spans."
       >
-      <div style={{ minWidth: 68 }}>
-      <InlineSwitch
-        value={rateZero}
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"traceID"}
+        portalOrigin=""
+        query={traceId}
         disabled={false}
-        transparent={false}
-        onChange={(event: ChangeEvent<HTMLInputElement>) => {
-            let checked = event.target.checked;
-            onChange({ ...query, rateZero: checked });
+        onChange={(value: string) => {
+            onChange({ ...query, traceId: value });
             if( requery ) {
                 onRunQuery();
             }
         }}
+        onBlur={() => {}}
       />
       </div>
       </InlineField>
 }
-{ (mode === "metric") && rate &&
+{ (mode === "trace") &&
       <InlineField
-        label="Interval"
+        label="Span ID"
         labelWidth={12}
-        tooltip="Rate interval, by default it's using the value of the parameter $interval."
+        tooltip="Span id field of the spans."
       >
-      <div style={{ minWidth: 245 }}>
+      <div style={{ minWidth: 150 }}>
       <QueryField
-        placeholder={"$interval"}
+        placeholder={"spanID"}
         portalOrigin=""
-        query={rateInterval}
+        query={traceSpanId}
         disabled={false}
         onChange={(value: string) => {
-            onChange({ ...query, rateInterval: value });
+            onChange({ ...query, traceSpanId: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "trace") &&
+      <InlineField
+        label="Parent ID"
+        labelWidth={12}
+        tooltip="Parent span id field, empty for the root span."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"parentSpanID"}
+        portalOrigin=""
+        query={traceParentSpanId}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, traceParentSpanId: value });
             if( requery ) {
                 onRunQuery();
             }
@@ -323,41 +541,756 @@ export function QueryEditor({ query, onChange, onRunQuery }: Props) {
 }
       </HorizontalGroup>
       <HorizontalGroup>
-{ (mode === "metric") && rate &&
+{ (mode === "trace") &&
       <InlineField
-        label="Function"
+        label="Service"
         labelWidth={12}
-        tooltip="Apply rate functions to the data value."
+        tooltip="Service name field of the spans."
       >
-      <Select
-        isMulti={true}
-        isClearable={true}
-        backspaceRemovesValue={false}
-        onChange={(selected: SelectableValue<string>) => {
-            onChange({ ...query, rateFunctions: selected.map( (v: SelectableValue<string>) => v.value ) });
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"serviceName"}
+        portalOrigin=""
+        query={traceServiceName}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, traceServiceName: value });
             if( requery ) {
                 onRunQuery();
             }
         }}
-        options={
-            [ { value: "count", label: "Count" }
-            , { value: "absence", label: "Absence" }
-            , { value: "sum", label: "Sum" }
-            , { value: "average", label: "Average" }
-            , { value: "median", label: "Median" }
-            , { value: "quantile25", label: "Quantile25" }
-            , { value: "quantile75", label: "Quantile75" }
-            , { value: "quantile95", label: "Quantile95" }
-            , { value: "quantile99", label: "Quantile99" }
-            , { value: "stddev", label: "StdDev" }
-            ]
-        }
-        isSearchable={true}
-        maxMenuHeight={500}
-        placeholder={""}
-        noOptionsMessage={"No options found"}
-        value={ rateFunctions }
-        width={75.5}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "trace") &&
+      <InlineField
+        label="Operation"
+        labelWidth={12}
+        tooltip="Operation name field of the spans."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"operationName"}
+        portalOrigin=""
+        query={traceOperationName}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, traceOperationName: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "trace") &&
+      <InlineField
+        label="Start"
+        labelWidth={12}
+        tooltip="Start time field, a datetime or epoch milliseconds."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"startTime"}
+        portalOrigin=""
+        query={traceStartTime}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, traceStartTime: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "trace") &&
+      <InlineField
+        label="Duration"
+        labelWidth={12}
+        tooltip="Duration field, a SurrealDB duration or milliseconds."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"duration"}
+        portalOrigin=""
+        query={traceDuration}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, traceDuration: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "trace") &&
+      <InlineField
+        label="Tags"
+        labelWidth={12}
+        tooltip="Tags field, an object of key value pairs."
+      >
+      <div style={{ minWidth: 150 }}>
+      <QueryField
+        placeholder={"tags"}
+        portalOrigin=""
+        query={traceTags}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, traceTags: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+      </HorizontalGroup>
+      <HorizontalGroup>
+{ (mode === "metric") &&
+      <InlineField
+        label="Group By"
+        labelWidth={12}
+        tooltip="Enable metric grouping."
+      >
+      <InlineSwitch
+        value={group}
+        disabled={false}
+        transparent={false}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let checked = event.target.checked;
+            onChange({ ...query, group: checked });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+{ (mode === "metric") && group &&
+      <InlineField
+        label="Field"
+        labelWidth={14}
+        tooltip="Metric group by field."
+      >
+      <div style={{ minWidth: 245 }}>
+      <QueryField
+        placeholder={"group"}
+        portalOrigin=""
+        query={groupBy}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, groupBy: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+          />
+          </div>
+        </InlineField>
+}
+      </HorizontalGroup>
+      <HorizontalGroup>
+{ (mode === "metric") && group &&
+      <InlineField
+        label="Limit"
+        labelWidth={12}
+        tooltip="Keep only the top or bottom K groups ranked by the aggregate of their values."
+      >
+      <Select
+        isMulti={false}
+        isClearable={false}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            let count = topK || bottomK || 5;
+            onChange({ ...query
+                     , topK: selected.value === "top" ? count : undefined
+                     , bottomK: selected.value === "bottom" ? count : undefined
+                     });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "", label: "None" }
+            , { value: "top", label: "Top K" }
+            , { value: "bottom", label: "Bottom K" }
+            ]
+        }
+        isSearchable={false}
+        maxMenuHeight={500}
+        noOptionsMessage={"No options found"}
+        value={ groupLimit }
+        width={14}
+      />
+      </InlineField>
+}
+{ (mode === "metric") && group && groupLimit !== "" &&
+      <InlineField
+        label="K"
+        labelWidth={6}
+        tooltip="Number of groups to keep."
+      >
+      <Input
+        type="number"
+        min={1}
+        value={ topK || bottomK }
+        width={10}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let count = Number(event.target.value) || undefined;
+            onChange({ ...query
+                     , topK: groupLimit === "top" ? count : undefined
+                     , bottomK: groupLimit === "bottom" ? count : undefined
+                     });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+{ (mode === "metric") && group &&
+      <InlineField
+        label="Order"
+        labelWidth={12}
+        tooltip="Order of the grouped series, by group key, first appearance or descending value."
+      >
+      <Select
+        isMulti={false}
+        isClearable={false}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, groupOrder: selected.value || "key" });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "key", label: "Key" }
+            , { value: "appearance", label: "Appearance" }
+            , { value: "value", label: "Value" }
+            ]
+        }
+        isSearchable={false}
+        maxMenuHeight={500}
+        noOptionsMessage={"No options found"}
+        value={ groupOrder || "key" }
+        width={14}
+      />
+      </InlineField>
+}
+{ (mode === "metric") && group && (groupLimit !== "" || groupOrder === "value") &&
+      <InlineField
+        label="Rank By"
+        labelWidth={12}
+        tooltip="Aggregate of the group values used for the ranking and the value order."
+      >
+      <Select
+        isMulti={false}
+        isClearable={false}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, groupRankBy: selected.value || "sum" });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "sum", label: "Sum" }
+            , { value: "avg", label: "Average" }
+            , { value: "max", label: "Max" }
+            , { value: "last", label: "Last" }
+            ]
+        }
+        isSearchable={false}
+        maxMenuHeight={500}
+        noOptionsMessage={"No options found"}
+        value={ groupRankBy || "sum" }
+        width={14}
+      />
+      </InlineField>
+}
+{ (mode === "metric") && group && groupLimit !== "" &&
+      <InlineField
+        label="Other"
+        labelWidth={12}
+        tooltip="Fold the remaining groups into a single 'other' series."
+      >
+      <InlineSwitch
+        value={groupOther}
+        disabled={false}
+        transparent={false}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let checked = event.target.checked;
+            onChange({ ...query, groupOther: checked });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+      </HorizontalGroup>
+      <HorizontalGroup>
+{ (mode === "metric") &&
+      <InlineField
+        label="Rate"
+        labelWidth={12}
+        tooltip="Enable rate computation."
+      >
+      <InlineSwitch
+        value={rate}
+        disabled={false}
+        transparent={false}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let checked = event.target.checked;
+            onChange({ ...query, rate: checked });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+{ (mode === "metric") && rate &&
+      <InlineField
+        label="Zero Vector"
+        labelWidth={14}
+        tooltip="Convert the null vector to a zero vector."
+      >
+      <div style={{ minWidth: 68 }}>
+      <InlineSwitch
+        value={rateZero}
+        disabled={false}
+        transparent={false}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let checked = event.target.checked;
+            onChange({ ...query, rateZero: checked });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </div>
+      </InlineField>
+}
+{ (mode === "metric") && rate &&
+      <InlineField
+        label="Interval"
+        labelWidth={12}
+        tooltip="Rate interval, by default it's using the value of the parameter $interval."
+      >
+      <div style={{ minWidth: 245 }}>
+      <QueryField
+        placeholder={"$interval"}
+        portalOrigin=""
+        query={rateInterval}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, rateInterval: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+      </HorizontalGroup>
+      <HorizontalGroup>
+{ (mode === "metric") && rate &&
+      <InlineField
+        label="Function"
+        labelWidth={12}
+        tooltip="Apply rate functions to the data value."
+      >
+      <Select
+        isMulti={true}
+        isClearable={true}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, rateFunctions: selected.map( (v: SelectableValue<string>) => v.value ) });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "count", label: "Count" }
+            , { value: "absence", label: "Absence" }
+            , { value: "sum", label: "Sum" }
+            , { value: "average", label: "Average" }
+            , { value: "median", label: "Median" }
+            , { value: "quantile25", label: "Quantile25" }
+            , { value: "quantile75", label: "Quantile75" }
+            , { value: "quantile95", label: "Quantile95" }
+            , { value: "quantile99", label: "Quantile99" }
+            , { value: "stddev", label: "StdDev" }
+            ]
+        }
+        isSearchable={true}
+        maxMenuHeight={500}
+        placeholder={""}
+        noOptionsMessage={"No options found"}
+        value={ rateFunctions }
+        width={75.5}
+      />
+    </InlineField>
+}
+{ (mode === "metric") && !rate &&
+      <InlineField
+        label="Downsample"
+        labelWidth={12}
+        tooltip="Reduce each series to the max data points of the panel with LTTB or the min and max per bucket."
+      >
+      <Select
+        isMulti={false}
+        isClearable={false}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, downsample: selected.value || undefined });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "", label: "None" }
+            , { value: "lttb", label: "LTTB" }
+            , { value: "minmax", label: "Min/Max" }
+            ]
+        }
+        isSearchable={false}
+        maxMenuHeight={500}
+        noOptionsMessage={"No options found"}
+        value={ downsample || "" }
+        width={14}
+      />
+    </InlineField>
+}
+{ (mode === "metric") &&
+      <InlineField
+        label="Format"
+        labelWidth={12}
+        tooltip="Frame format of the series, a frame per series (multi), one frame with labels (wide) or one frame with the labels as columns (long). Without grouping the string fields are the labels and further numeric fields are kept as values."
+      >
+      <Select
+        isMulti={false}
+        isClearable={false}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, frameFormat: selected.value || "multi" });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "multi", label: "Multi" }
+            , { value: "wide", label: "Wide" }
+            , { value: "long", label: "Long" }
+            ]
+        }
+        isSearchable={false}
+        maxMenuHeight={500}
+        noOptionsMessage={"No options found"}
+        value={ frameFormat || "multi" }
+        width={14}
+      />
+    </InlineField>
+}
+    </HorizontalGroup>
+    <HorizontalGroup>
+{ (mode === "histogram") &&
+      <InlineField
+        label="Buckets"
+        labelWidth={12}
+        tooltip="Linear buckets of a width, exponential buckets of a factor or explicit upper bounds. Values below the first bucket are counted in it, values above the last bucket in '+Inf'."
+      >
+      <Select
+        isMulti={false}
+        isClearable={false}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, histogramBuckets: selected.value || "linear" });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "linear", label: "Linear" }
+            , { value: "exponential", label: "Exponential" }
+            , { value: "explicit", label: "Explicit" }
+            ]
+        }
+        isSearchable={false}
+        maxMenuHeight={500}
+        noOptionsMessage={"No options found"}
+        value={ histogramBuckets || "linear" }
+        width={14}
+      />
+      </InlineField>
+}
+{ (mode === "histogram") && histogramBuckets !== "explicit" &&
+      <InlineField
+        label="Start"
+        labelWidth={8}
+        tooltip="Lower bound of the first linear bucket or upper bound of the first exponential bucket."
+      >
+      <Input
+        type="number"
+        placeholder={ histogramBuckets === "exponential" ? "1" : "0" }
+        value={ histogramStart ?? "" }
+        width={10}
+        onChange={onNumberChange("histogramStart")}
+      />
+      </InlineField>
+}
+{ (mode === "histogram") && (histogramBuckets || "linear") === "linear" &&
+      <InlineField
+        label="Width"
+        labelWidth={8}
+        tooltip="Width of the linear buckets."
+      >
+      <Input
+        type="number"
+        min={0}
+        placeholder={"1"}
+        value={ histogramWidth ?? "" }
+        width={10}
+        onChange={onNumberChange("histogramWidth")}
+      />
+      </InlineField>
+}
+{ (mode === "histogram") && histogramBuckets === "exponential" &&
+      <InlineField
+        label="Factor"
+        labelWidth={8}
+        tooltip="Factor between the exponential bucket bounds, greater than 1."
+      >
+      <Input
+        type="number"
+        min={1}
+        placeholder={"2"}
+        value={ histogramFactor ?? "" }
+        width={10}
+        onChange={onNumberChange("histogramFactor")}
+      />
+      </InlineField>
+}
+{ (mode === "histogram") && histogramBuckets !== "explicit" &&
+      <InlineField
+        label="Count"
+        labelWidth={8}
+        tooltip="Number of buckets."
+      >
+      <Input
+        type="number"
+        min={1}
+        placeholder={"10"}
+        value={ histogramCount ?? "" }
+        width={10}
+        onChange={onNumberChange("histogramCount")}
+      />
+      </InlineField>
+}
+{ (mode === "histogram") && histogramBuckets === "explicit" &&
+      <InlineField
+        label="Bounds"
+        labelWidth={8}
+        tooltip="Strictly increasing upper bounds of the buckets, separated by commas."
+      >
+      <Input
+        placeholder={"0.1, 0.5, 1"}
+        defaultValue={ (histogramBounds || []).join(", ") }
+        width={30}
+        onBlur={(event: React.FocusEvent<HTMLInputElement>) => {
+            let bounds = event.target.value.split(",")
+                .map( (bound: string) => bound.trim() )
+                .filter( (bound: string) => bound !== "" )
+                .map(Number);
+            onChange({ ...query, histogramBounds: bounds });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+{ (mode === "histogram") &&
+      <InlineField
+        label="Format"
+        labelWidth={8}
+        tooltip="Heatmap rows with a field per bucket or heatmap cells with the bucket bounds."
+      >
+      <Select
+        isMulti={false}
+        isClearable={false}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, histogramFormat: selected.value || "rows" });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "rows", label: "Rows" }
+            , { value: "cells", label: "Cells" }
+            ]
+        }
+        isSearchable={false}
+        maxMenuHeight={500}
+        noOptionsMessage={"No options found"}
+        value={ histogramFormat || "rows" }
+        width={14}
+      />
+      </InlineField>
+}
+    </HorizontalGroup>
+      <HorizontalGroup>
+{ (mode !== "explain") &&
+      <InlineField
+        label="Limit"
+        labelWidth={12}
+        tooltip="Maximum number of rows of the query result, up to the max rows of the data source."
+      >
+      <Input
+        type="number"
+        min={0}
+        placeholder={"0"}
+        value={ limit ?? "" }
+        width={10}
+        onChange={onNumberChange("limit")}
+      />
+      </InlineField>
+}
+{ (mode === "raw" || mode === "log") &&
+      <InlineField
+        label="Paginate"
+        labelWidth={12}
+        tooltip="Return a page of the rows, by default 1000 rows, the frame meta data contains the cursor of the next page."
+      >
+      <InlineSwitch
+        value={paginate}
+        disabled={false}
+        transparent={false}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let checked = event.target.checked;
+            onChange({ ...query, paginate: checked, cursor: checked ? cursor : undefined });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+      />
+      </InlineField>
+}
+{ (mode === "raw" || mode === "log") && paginate &&
+      <InlineField
+        label="Cursor"
+        labelWidth={12}
+        tooltip="Cursor of the page, from the frame meta data of the previous page."
+      >
+      <div style={{ minWidth: 245 }}>
+      <QueryField
+        placeholder={"first page"}
+        portalOrigin=""
+        query={cursor}
+        disabled={false}
+        onChange={(value: string) => {
+            onChange({ ...query, cursor: value });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        onBlur={() => {}}
+      />
+      </div>
+      </InlineField>
+}
+      </HorizontalGroup>
+    <HorizontalGroup>
+{ (mode === "metric") &&
+      <InlineField
+        label="Transform"
+        labelWidth={12}
+        tooltip="Window transforms applied in the given order to every value field, after the rate and downsampling."
+      >
+      <Select
+        isMulti={true}
+        isClearable={true}
+        backspaceRemovesValue={false}
+        onChange={(selected: SelectableValue<string>) => {
+            onChange({ ...query, transforms: selected.map( (v: SelectableValue<string>) =>
+                transformOf(v.value || "") || { function: v.value || "" }
+            ) });
+            if( requery ) {
+                onRunQuery();
+            }
+        }}
+        options={
+            [ { value: "movingAverage", label: "Moving Average" }
+            , { value: "ewma", label: "EWMA" }
+            , { value: "cumulativeSum", label: "Cumulative Sum" }
+            , { value: "derivative", label: "Derivative" }
+            , { value: "nonNegativeDerivative", label: "Non-Negative Derivative" }
+            ]
+        }
+        isSearchable={true}
+        maxMenuHeight={500}
+        placeholder={""}
+        noOptionsMessage={"No options found"}
+        value={ (transforms || []).map( (transform: MyTransform) => transform.function ) }
+        width={45}
+      />
+    </InlineField>
+}
+{ (mode === "metric") && transformOf("movingAverage") &&
+      <InlineField
+        label="Window"
+        labelWidth={12}
+        tooltip="Number of values of the moving average, by default 5."
+      >
+      <Input
+        type="number"
+        min={1}
+        placeholder={"5"}
+        value={ transformOf("movingAverage")?.window ?? "" }
+        width={14}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let value = event.target.value;
+            onTransformChange("movingAverage", { window: value === "" ? undefined : Number(value) });
+        }}
+      />
+    </InlineField>
+}
+{ (mode === "metric") && transformOf("ewma") &&
+      <InlineField
+        label="Alpha"
+        labelWidth={12}
+        tooltip="Smoothing factor of the EWMA in (0, 1], by default 0.5."
+      >
+      <Input
+        type="number"
+        min={0}
+        max={1}
+        step={0.1}
+        placeholder={"0.5"}
+        value={ transformOf("ewma")?.alpha ?? "" }
+        width={14}
+        onChange={(event: ChangeEvent<HTMLInputElement>) => {
+            let value = event.target.value;
+            onTransformChange("ewma", { alpha: value === "" ? undefined : Number(value) });
+        }}
       />
     </InlineField>
 }
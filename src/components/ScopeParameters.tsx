@@ -0,0 +1,152 @@
+import React, { ChangeEvent, useState } from 'react';
+import { Button, InlineField, InlineFieldRow, InlineSwitch, Input, SecretInput } from '@grafana/ui';
+import { DataSourcePluginOptionsEditorProps } from '@grafana/data';
+import { MyDataSourceOptions } from '../types';
+
+// secret parameters are secure json data, see 'scopeParameterPrefix' in pkg/plugin/auth.go
+const secretPrefix = 'scopeParameter.';
+
+interface Parameter {
+  name: string;
+  value: string;
+  secret: boolean;
+  configured: boolean;
+  // provisioned values which are not strings are kept unless edited
+  typed?: unknown;
+}
+
+type Props = DataSourcePluginOptionsEditorProps<MyDataSourceOptions>;
+
+function initialParameters(options: Props['options']): Parameter[] {
+  const parameters: Parameter[] = Object.entries(options.jsonData.scopeParameters || {}).map(([name, value]) => ({
+    name,
+    value: typeof value === 'string' ? value : JSON.stringify(value),
+    secret: false,
+    configured: false,
+    typed: typeof value === 'string' ? undefined : value,
+  }));
+
+  Object.entries(options.secureJsonFields || {}).forEach(([key, configured]) => {
+    if (configured && key.startsWith(secretPrefix)) {
+      parameters.push({ name: key.slice(secretPrefix.length), value: '', secret: true, configured: true });
+    }
+  });
+
+  return parameters;
+}
+
+// edits the extra signin parameters, the secret ones are stored encrypted and
+// only their names are shown once they are saved
+export function ScopeParameters({ options, onOptionsChange }: Props) {
+  const [parameters, setParameters] = useState<Parameter[]>(() => initialParameters(options));
+
+  const onParametersChange = (changed: Parameter[]) => {
+    setParameters(changed);
+
+    const scopeParameters: Record<string, unknown> = {};
+    const secureJsonData: Record<string, string> = { ...options.secureJsonData };
+    const secureJsonFields: Record<string, boolean> = { ...options.secureJsonFields };
+
+    // secrets which are removed or renamed are reset
+    Object.keys(secureJsonFields)
+      .concat(Object.keys(secureJsonData))
+      .filter((key) => key.startsWith(secretPrefix))
+      .forEach((key) => {
+        const name = key.slice(secretPrefix.length);
+        if (changed.some((parameter) => parameter.secret && parameter.name === name) === false) {
+          secureJsonFields[key] = false;
+          secureJsonData[key] = '';
+        }
+      });
+
+    changed.forEach((parameter) => {
+      if (parameter.name === '') {
+        return;
+      }
+      if (parameter.secret === false) {
+        const unchanged = parameter.typed !== undefined && JSON.stringify(parameter.typed) === parameter.value;
+        scopeParameters[parameter.name] = unchanged ? parameter.typed : parameter.value;
+      } else if (parameter.configured === false) {
+        secureJsonFields[secretPrefix + parameter.name] = false;
+        secureJsonData[secretPrefix + parameter.name] = parameter.value;
+      }
+    });
+
+    onOptionsChange({
+      ...options,
+      jsonData: {
+        ...options.jsonData,
+        scopeParameters,
+      },
+      secureJsonData,
+      secureJsonFields,
+    });
+  };
+
+  const onParameterChange = (index: number, changes: Partial<Parameter>) => {
+    onParametersChange(parameters.map((parameter, position) => (position === index ? { ...parameter, ...changes } : parameter)));
+  };
+
+  return (
+    <>
+      {parameters.map((parameter, index) => (
+        <InlineFieldRow key={index}>
+          <InlineField label="Parameter" labelWidth={20} tooltip="Name of the signin parameter.">
+            <Input
+              value={parameter.name}
+              placeholder="name"
+              width={20}
+              disabled={parameter.configured}
+              onChange={(event: ChangeEvent<HTMLInputElement>) => {
+                onParameterChange(index, { name: event.target.value });
+              }}
+            />
+          </InlineField>
+          <InlineField label="Value" tooltip="String value of the signin parameter.">
+            {parameter.secret ? (
+              <SecretInput
+                value={parameter.value}
+                width={30}
+                isConfigured={parameter.configured}
+                onChange={(event: ChangeEvent<HTMLInputElement>) => {
+                  onParameterChange(index, { value: event.target.value });
+                }}
+                onReset={() => onParameterChange(index, { value: '', configured: false })}
+              />
+            ) : (
+              <Input
+                value={parameter.value}
+                width={30}
+                onChange={(event: ChangeEvent<HTMLInputElement>) => {
+                  onParameterChange(index, { value: event.target.value });
+                }}
+              />
+            )}
+          </InlineField>
+          <InlineField label="Secret" tooltip="Store the value encrypted, it is redacted in the logs.">
+            <InlineSwitch
+              value={parameter.secret}
+              disabled={parameter.configured}
+              onChange={(event: ChangeEvent<HTMLInputElement>) => {
+                onParameterChange(index, { secret: event.target.checked });
+              }}
+            />
+          </InlineField>
+          <Button
+            variant="secondary"
+            icon="trash-alt"
+            aria-label="Remove parameter"
+            onClick={() => onParametersChange(parameters.filter((_, position) => position !== index))}
+          />
+        </InlineFieldRow>
+      ))}
+      <Button
+        variant="secondary"
+        icon="plus"
+        onClick={() => onParametersChange([...parameters, { name: '', value: '', secret: false, configured: false }])}
+      >
+        Add signin parameter
+      </Button>
+    </>
+  );
+}
@@ -48,6 +48,9 @@ export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptio
 	return {
 	    ...query,
 	    surql: getTemplateSrv().replace(query.surql, scopedVars),
+	    namespace: query.namespace && getTemplateSrv().replace(query.namespace, scopedVars),
+	    database: query.database && getTemplateSrv().replace(query.database, scopedVars),
+	    cursor: query.cursor && getTemplateSrv().replace(query.cursor, scopedVars),
 	};
     }
 
@@ -9,16 +9,57 @@ import
 export interface MyQuery extends DataQuery {
     mode: string;
     surql: string;
+    namespace?: string;
+    database?: string;
     requery: boolean;
     timestamp?: string;
     logMessage?: string;
     metricData?: string;
     group?: boolean;
     groupBy?: string;
+    groupRankBy?: string;
+    groupOther?: boolean;
+    groupOrder?: string;
+    topK?: number;
+    bottomK?: number;
     rate?: boolean;
     rateZero?: boolean;
     rateInterval?: string;
     rateFunctions?: string[];
+    transforms?: MyTransform[];
+    histogramBuckets?: string;
+    histogramStart?: number;
+    histogramWidth?: number;
+    histogramFactor?: number;
+    histogramCount?: number;
+    histogramBounds?: number[];
+    histogramFormat?: string;
+    downsample?: string;
+    frameFormat?: string;
+    geo?: boolean;
+    limit?: number;
+    paginate?: boolean;
+    cursor?: string;
+    explainFull?: boolean;
+    graphTitle?: string;
+    graphMainStat?: string;
+    graphSecondaryStat?: string;
+    graphColor?: string;
+    traceSearch?: boolean;
+    traceId?: string;
+    traceSpanId?: string;
+    traceParentSpanId?: string;
+    traceServiceName?: string;
+    traceOperationName?: string;
+    traceStartTime?: string;
+    traceDuration?: string;
+    traceTags?: string;
+}
+
+export interface MyTransform {
+    function: string;
+    window?: number;
+    alpha?: number;
 }
 
 export const DEFAULT_QUERY: Partial<MyQuery> =
@@ -36,6 +77,29 @@ export interface MyDataSourceOptions extends DataSourceJsonData {
     database?: string;
     scope?: string;
     username?: string;
+    authMethod?: string;
+    scopeParameters?: Record<string, any>;
+    forwardIdentity?: string;
+    oauthPassThru?: boolean;
+    transport?: string;
+    debug?: boolean;
+    maxRows?: number;
+    surrealVersion?: string;
+    access?: string;
+    allowedNamespaces?: string[];
+    allowedDatabases?: string[];
+    endpoints?: string[];
+    endpointStrategy?: string;
+    endpointCooldown?: string;
+    poolMinSize?: number;
+    poolMaxSize?: number;
+    poolIdleTimeout?: string;
+    poolHealthCheckInterval?: string;
+    scheme?: string;
+    tlsAuth?: boolean;
+    tlsAuthWithCACert?: boolean;
+    tlsSkipVerify?: boolean;
+    serverName?: string;
 }
 
 /**
@@ -43,4 +107,8 @@ export interface MyDataSourceOptions extends DataSourceJsonData {
  */
 export interface MySecureJsonData {
     password?: string;
+    token?: string;
+    tlsCACert?: string;
+    tlsClientCert?: string;
+    tlsClientKey?: string;
 }